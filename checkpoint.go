@@ -0,0 +1,18 @@
+package web_crawler
+
+import "github.com/jmwri/web-crawler/internal"
+
+// Checkpoint is the state of a crawl that was saved to CrawlConfig.StateDir, so that the crawl can be resumed
+type Checkpoint = internal.Checkpoint
+
+// DefaultCheckpointInterval is how often a crawl is checkpointed unless CrawlConfig.CheckpointInterval is set
+const DefaultCheckpointInterval = internal.DefaultCheckpointInterval
+
+// ErrStateDir is matched by the error returned when CrawlConfig.StateDir can't be created, before anything is crawled
+var ErrStateDir = internal.ErrStateDir
+
+// LoadCheckpoint loads the Checkpoint that was last saved to the state directory. Pass it to CrawlConfig.Resume,
+// along with the same config, to carry on with the crawl.
+func LoadCheckpoint(dir string) (*Checkpoint, error) {
+	return internal.LoadCheckpoint(dir)
+}
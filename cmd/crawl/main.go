@@ -1,24 +1,57 @@
 package main
 
 import (
+	"bufio"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	webcrawler "github.com/jmwri/web-crawler"
 	"net/url"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 )
 
 func init() {
 	flag.Usage = func() {
-		fmt.Fprintf(flag.CommandLine.Output(), "Usage of %s <target>:\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage of %s <target>...:\n", os.Args[0])
 		flag.PrintDefaults()
 	}
 }
 
 func main() {
-	sameDomainPtr := flag.Bool("sameDomain", true, "only crawl the same domain")
+	sameDomainPtr := flag.Bool("sameDomain", true, "only crawl URLs within -scope - false crawls all domains")
+	scopePtr := flag.String("scope", "host", "which URLs are on the same domain - host, subdomains or domain (registrable domain)")
+	ignoreSchemePtr := flag.Bool("ignoreScheme", false, "treat http and https URLs as the same site")
 	maxDepthPtr := flag.Int("maxDepth", 4, "crawl up to this depth - 0 for no limit")
+	strictDepthPtr := flag.Bool("strictDepth", false, "crawl one depth at a time, so every page is found at its shortest depth")
 	workersPtr := flag.Int("workers", 20, "number of workers")
+	maxPagesPtr := flag.Int("maxPages", 0, "crawl up to this many pages - 0 for no limit")
+	maxDurationPtr := flag.Duration("maxDuration", 0, "crawl for up to this long - 0 for no limit")
+	maxHostPagesPtr := flag.Int("maxHostPages", 0, "crawl up to this many pages on each host - 0 for no limit")
+	maxBytesPtr := flag.Int64("maxBytes", 0, "stop crawling after downloading this many bytes - 0 for no limit")
+	maxQueuePtr := flag.Int("maxQueue", webcrawler.DefaultMaxQueue, "max URLs waiting to be crawled at once")
+	priorityPtr := flag.String("priority", "bfs", "order to crawl URLs in - bfs (breadth first) or host (round robin between hosts)")
+	gracePtr := flag.Duration("grace", 10*time.Second, "how long in flight requests have to finish once -maxDuration is reached or the crawl is interrupted")
+	normalizePtr := flag.Bool("normalize", false, "normalize discovered URLs according to RFC 3986, ie sort query parameters")
+	stripParamsPtr := flag.String("stripParams", "", "comma separated query and path parameters to remove from discovered URLs - 'default' for common tracking and session parameters")
+	keepParamsPtr := flag.String("keepParams", "", "comma separated query parameters to keep in discovered URLs, removing all others")
+	var include, exclude patternsFlag
+	flag.Var(&include, "include", "only crawl URLs matching this pattern - regexp:, glob: or prefix:, defaulting to glob (repeatable)")
+	flag.Var(&exclude, "exclude", "never crawl URLs matching this pattern - regexp:, glob: or prefix:, defaulting to glob (repeatable)")
+	ratePtr := flag.Float64("rate", 0, "max requests per second to each host - 0 for no limit")
+	delayPtr := flag.Duration("delay", 0, "min delay between requests to each host")
+	hostWorkersPtr := flag.Int("hostWorkers", 0, "max concurrent requests to each host - 0 for no limit")
+	ignoreRobotsPtr := flag.Bool("ignoreRobots", false, "ignore robots.txt - only use on sites you own")
+	checkResourcesPtr := flag.Bool("checkResources", false, "check that images, scripts, stylesheets and other resources load, but not form actions")
+	formatPtr := flag.String("format", formatText, "output format - text, json (pages and edges) or csv (edges)")
+	headProbePtr := flag.Bool("headProbe", false, "send a HEAD request to check the content type before each page is downloaded")
+	seedsPtr := flag.String("seeds", "", "file of URLs to crawl from as well as the targets, one per line - '-' reads them from stdin")
+	statePtr := flag.String("state", "", "directory to checkpoint the crawl to, so that it can be resumed")
+	resumePtr := flag.String("resume", "", "resume the crawl that was checkpointed to this directory, and keep checkpointing to it")
 	helpPtr := flag.Bool("h", false, "show help")
 
 	flag.Parse()
@@ -29,27 +62,277 @@ func main() {
 		flag.Usage()
 		os.Exit(0)
 	}
-	if len(args) != 1 {
+	targets := args
+	if *seedsPtr != "" {
+		listed, err := readSeeds(*seedsPtr)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to read seeds: %s\n", err)
+			os.Exit(1)
+		}
+		targets = append(targets, listed...)
+	}
+	// The targets can be left out when resuming, as the checkpoint has them
+	if len(targets) == 0 && *resumePtr == "" {
 		flag.Usage()
 		os.Exit(1)
 	}
-	target := args[0]
-	fmt.Printf("crawling '%s'\n", target)
+	format := *formatPtr
+	if format != formatText && format != formatJSON && format != formatCSV {
+		fmt.Fprintf(os.Stderr, "invalid format: %s\n", format)
+		os.Exit(1)
+	}
+	// Progress goes to stderr for the machine readable formats, so that stdout can be piped
+	status := os.Stdout
+	if format != formatText {
+		status = os.Stderr
+	}
+
+	stateDir := *statePtr
+	var checkpoint *webcrawler.Checkpoint
+	if *resumePtr != "" {
+		var err error
+		checkpoint, err = webcrawler.LoadCheckpoint(*resumePtr)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if stateDir == "" {
+			stateDir = *resumePtr
+		}
+		fmt.Fprintf(status, "resuming from '%s' with %d pages crawled and %d pending\n", *resumePtr, checkpoint.Pages(), checkpoint.Pending())
+	}
+
+	seeds := make([]*url.URL, 0, len(targets))
+	for _, target := range targets {
+		fmt.Fprintf(status, "crawling '%s'\n", target)
+		parsedUrl, err := url.Parse(target)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid target: %s\n", err)
+			os.Exit(1)
+		}
+		seeds = append(seeds, parsedUrl)
+	}
+	if len(seeds) == 0 {
+		seeds = checkpoint.Seeds()
+	}
+
+	opts := []webcrawler.Option{
+		webcrawler.WithRateLimit(*ratePtr),
+		webcrawler.WithCrawlDelay(*delayPtr),
+		webcrawler.WithHostConcurrency(*hostWorkersPtr),
+	}
+	if *ignoreRobotsPtr {
+		opts = append(opts, webcrawler.WithIgnoreRobots())
+	}
+	if *headProbePtr {
+		opts = append(opts, webcrawler.WithHeadProbe())
+	}
+	if *stripParamsPtr != "" {
+		params := paramNames(*stripParamsPtr)
+		opts = append(opts, webcrawler.WithModifiers(webcrawler.StripQueryParams(params...), webcrawler.StripPathParams(params...)))
+	}
+	if *keepParamsPtr != "" {
+		opts = append(opts, webcrawler.WithModifiers(webcrawler.KeepQueryParams(paramNames(*keepParamsPtr)...)))
+	}
+	if *normalizePtr {
+		opts = append(opts, webcrawler.WithModifiers(webcrawler.RFC3986Modifiers()...))
+	}
+	crawler := webcrawler.NewCrawler(opts...)
+
+	scope, err := webcrawler.ParseScope(*scopePtr)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if !*sameDomainPtr {
+		scope = webcrawler.ScopeAll
+	}
+	priority, err := parsePriority(*priorityPtr)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 
-	// TODO: validate initial target
-	parsedUrl, _ := url.Parse(target)
+	// The first SIGINT or SIGTERM stops the crawl so that the partial results are still written, and the second exits
+	stop := make(chan struct{})
+	interrupted := make(chan os.Signal, 1)
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go handleSignals(signals, stop, interrupted, *gracePtr)
 
-	res, err := webcrawler.DefaultCrawler.Crawl(parsedUrl, *sameDomainPtr, *maxDepthPtr, *workersPtr)
+	res, err := crawler.CrawlWithConfig(context.Background(), webcrawler.CrawlConfig{
+		Seeds:           seeds,
+		Scope:           scope,
+		IgnoreScheme:    *ignoreSchemePtr,
+		MaxDepth:        *maxDepthPtr,
+		Workers:         *workersPtr,
+		MaxPages:        *maxPagesPtr,
+		MaxDuration:     *maxDurationPtr,
+		MaxPagesPerHost: *maxHostPagesPtr,
+		MaxBytes:        *maxBytesPtr,
+		GracePeriod:     *gracePtr,
+		Stop:            stop,
+		CheckResources:  *checkResourcesPtr,
+		Include:         include,
+		Exclude:         exclude,
+		Priority:        priority,
+		MaxQueue:        *maxQueuePtr,
+		StrictDepth:     *strictDepthPtr,
+		StateDir:        stateDir,
+		Resume:          checkpoint,
+	})
+	// Signals go back to their default behaviour once the crawl is over, so that writing the output can be interrupted
+	signal.Stop(signals)
+	// An invalid config or an unusable state dir means nothing was crawled. Any other error, such as failing to save
+	// the final checkpoint, still returns the results, so they are written before exiting.
 	if err != nil {
-		panic(err)
+		fmt.Fprintln(os.Stderr, err)
+		if errors.Is(err, webcrawler.ErrInvalidConfig) || errors.Is(err, webcrawler.ErrStateDir) {
+			os.Exit(1)
+		}
 	}
+	crawlErr := err
 
-	for page, links := range res.URLs() {
-		if links == nil {
+	switch format {
+	case formatJSON:
+		err = writeJSON(os.Stdout, res)
+	case formatCSV:
+		err = writeCSV(os.Stdout, res)
+	default:
+		for page, links := range res.URLs() {
+			if links == nil {
+				continue
+			}
+			fmt.Println(page, links)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to write output: %s\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Fprintf(status, "crawled %d pages\n", len(res.URLs()))
+	stats := res.QueueStats()
+	fmt.Fprintf(status, "queued %d URLs, at most %d at once, dropped %d\n", stats.Queued, stats.MaxLen, stats.Dropped)
+	if res.Truncated() {
+		fmt.Fprintf(status, "crawl truncated: %s\n", res.TruncateReason())
+	}
+	if format == formatText {
+		printRedirects(res)
+		printExcluded(res)
+		printFailures(res)
+	}
+
+	// A signal that arrived after the crawl finished didn't stop it, so the results are complete
+	select {
+	case sig := <-interrupted:
+		if res.TruncateReason() != webcrawler.TruncatedStopped {
+			break
+		}
+		fmt.Fprintln(os.Stderr, "crawl interrupted, results are incomplete")
+		if stateDir != "" {
+			fmt.Fprintf(os.Stderr, "resume with -resume %s\n", stateDir)
+		}
+		os.Exit(exitCode(sig))
+	default:
+	}
+	if crawlErr != nil {
+		os.Exit(1)
+	}
+}
+
+// handleSignals closes stop on the first signal and passes it on to interrupted, so that the crawl finishes with the
+// pages that are in flight. The second signal exits straight away.
+func handleSignals(signals <-chan os.Signal, stop chan<- struct{}, interrupted chan<- os.Signal, grace time.Duration) {
+	sig := <-signals
+	fmt.Fprintf(os.Stderr, "received %s, waiting up to %s for in flight requests - send again to exit now\n", sig, grace)
+	interrupted <- sig
+	close(stop)
+
+	sig = <-signals
+	fmt.Fprintf(os.Stderr, "received %s, exiting\n", sig)
+	os.Exit(exitCode(sig))
+}
+
+// exitCode returns the conventional exit code for a process that was stopped by the signal
+func exitCode(sig os.Signal) int {
+	if s, ok := sig.(syscall.Signal); ok {
+		return 128 + int(s)
+	}
+	return 1
+}
+
+// readSeeds returns the URLs listed in the file, or stdin if the path is "-". Blank lines and lines starting with #
+// are skipped.
+func readSeeds(path string) ([]string, error) {
+	r := os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+	seeds := make([]string, 0)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
 			continue
 		}
-		fmt.Println(page, links)
+		seeds = append(seeds, line)
+	}
+	return seeds, scanner.Err()
+}
+
+// paramNames splits a comma separated list of parameter names, replacing "default" with
+// webcrawler.DefaultTrackingParams
+func paramNames(list string) []string {
+	names := make([]string, 0)
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		switch name {
+		case "":
+		case "default":
+			names = append(names, webcrawler.DefaultTrackingParams...)
+		default:
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// parsePriority returns the webcrawler.Priority with the given name: bfs or host
+func parsePriority(name string) (webcrawler.Priority, error) {
+	switch name {
+	case "bfs":
+		return webcrawler.BreadthFirst, nil
+	case "host":
+		return webcrawler.HostRoundRobin, nil
+	default:
+		return nil, fmt.Errorf("unknown priority '%s'", name)
+	}
+}
+
+// patternsFlag is a flag.Value that parses each value given for a repeatable flag as a webcrawler.URLPattern
+type patternsFlag []webcrawler.URLPattern
+
+// String returns the patterns, separated by commas
+func (f *patternsFlag) String() string {
+	patterns := make([]string, len(*f))
+	for i, p := range *f {
+		patterns[i] = p.String()
 	}
+	return strings.Join(patterns, ",")
+}
 
-	fmt.Printf("crawled %d pages\n", len(res.URLs()))
+// Set parses and adds a pattern
+func (f *patternsFlag) Set(s string) error {
+	p, err := webcrawler.ParseURLPattern(s)
+	if err != nil {
+		return err
+	}
+	*f = append(*f, p)
+	return nil
 }
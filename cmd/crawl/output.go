@@ -0,0 +1,251 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	webcrawler "github.com/jmwri/web-crawler"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Output formats supported by the -format flag
+const (
+	formatText = "text"
+	formatJSON = "json"
+	formatCSV  = "csv"
+)
+
+// jsonOutput is the document written by writeJSON
+type jsonOutput struct {
+	Target    string            `json:"target"`
+	Seeds     []string          `json:"seeds"`
+	Truncated string            `json:"truncated,omitempty"`
+	Pages     []jsonPage        `json:"pages"`
+	Excluded  map[string]string `json:"excluded,omitempty"`
+	Sitemaps  []string          `json:"sitemaps,omitempty"`
+}
+
+// jsonPage is a single webcrawler.Page in jsonOutput
+type jsonPage struct {
+	URL         string         `json:"url"`
+	Depth       int            `json:"depth"`
+	Origin      string         `json:"origin,omitempty"`
+	Seed        string         `json:"seed"`
+	Kind        string         `json:"kind"`
+	StatusCode  int            `json:"status_code,omitempty"`
+	ContentType string         `json:"content_type,omitempty"`
+	Size        int64          `json:"size"`
+	LatencyMS   int64          `json:"latency_ms"`
+	FinalURL    string         `json:"final_url"`
+	Redirects   []jsonRedirect `json:"redirects,omitempty"`
+	Duplicate   bool           `json:"duplicate,omitempty"`
+	NoFollow    bool           `json:"no_follow,omitempty"`
+	NoIndex     bool           `json:"no_index,omitempty"`
+	Error       *jsonPageError `json:"error,omitempty"`
+	Edges       []jsonEdge     `json:"edges"`
+}
+
+// jsonRedirect is a single webcrawler.Redirect in jsonPage
+type jsonRedirect struct {
+	URL        string `json:"url"`
+	StatusCode int    `json:"status_code"`
+}
+
+// jsonPageError is a webcrawler.PageError in jsonPage
+type jsonPageError struct {
+	Kind     string `json:"kind"`
+	Attempts int    `json:"attempts"`
+	Message  string `json:"message"`
+}
+
+// jsonEdge is a single webcrawler.Edge in jsonPage
+type jsonEdge struct {
+	URL      string   `json:"url"`
+	Kind     string   `json:"kind"`
+	NoFollow bool     `json:"no_follow,omitempty"`
+	Text     string   `json:"text,omitempty"`
+	Title    string   `json:"title,omitempty"`
+	Rel      []string `json:"rel,omitempty"`
+	Target   string   `json:"target,omitempty"`
+	Image    bool     `json:"image,omitempty"`
+	Alt      string   `json:"alt,omitempty"`
+}
+
+// sortedPages returns the URL of each page in res, sorted
+func sortedPages(res webcrawler.Result) []string {
+	urls := make([]string, 0, len(res.Pages()))
+	for u := range res.Pages() {
+		urls = append(urls, u)
+	}
+	sort.Strings(urls)
+	return urls
+}
+
+// writeJSON writes every page in res, along with its edges, as a JSON document
+func writeJSON(w io.Writer, res webcrawler.Result) error {
+	out := jsonOutput{
+		Target:   res.Target().String(),
+		Seeds:    make([]string, len(res.Seeds())),
+		Pages:    make([]jsonPage, 0, len(res.Pages())),
+		Excluded: res.Excluded(),
+		Sitemaps: res.Sitemaps(),
+	}
+	for i, seed := range res.Seeds() {
+		out.Seeds[i] = seed.String()
+	}
+	if res.Truncated() {
+		out.Truncated = res.TruncateReason().String()
+	}
+	for _, u := range sortedPages(res) {
+		p := res.Pages()[u]
+		jp := jsonPage{
+			URL:         p.URL,
+			Depth:       p.Depth,
+			Origin:      p.Origin,
+			Seed:        p.Seed,
+			Kind:        p.Kind.String(),
+			StatusCode:  p.StatusCode,
+			ContentType: p.ContentType,
+			Size:        p.Size,
+			LatencyMS:   p.Latency.Milliseconds(),
+			FinalURL:    p.FinalURL,
+			Duplicate:   p.Duplicate,
+			NoFollow:    p.NoFollow,
+			NoIndex:     p.NoIndex,
+			Edges:       make([]jsonEdge, len(p.Edges)),
+		}
+		for _, r := range p.Redirects {
+			jp.Redirects = append(jp.Redirects, jsonRedirect{URL: r.URL, StatusCode: r.StatusCode})
+		}
+		if p.Err != nil {
+			jp.Error = &jsonPageError{Kind: p.Err.Kind.String(), Attempts: p.Err.Attempts, Message: p.Err.Err.Error()}
+		}
+		for i, e := range p.Edges {
+			jp.Edges[i] = jsonEdge{
+				URL:      e.URL,
+				Kind:     e.Kind.String(),
+				NoFollow: e.NoFollow,
+				Text:     e.Text,
+				Title:    e.Title,
+				Rel:      e.Rel,
+				Target:   e.Target,
+				Image:    e.Image,
+				Alt:      e.Alt,
+			}
+		}
+		out.Pages = append(out.Pages, jp)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+// writeCSV writes every edge in res as a row of CSV, with a header row
+func writeCSV(w io.Writer, res webcrawler.Result) error {
+	cw := csv.NewWriter(w)
+	header := []string{"source", "url", "kind", "nofollow", "text", "title", "rel", "target", "image", "alt"}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, u := range sortedPages(res) {
+		for _, e := range res.Pages()[u].Edges {
+			row := []string{
+				u,
+				e.URL,
+				e.Kind.String(),
+				strconv.FormatBool(e.NoFollow),
+				e.Text,
+				e.Title,
+				strings.Join(e.Rel, " "),
+				e.Target,
+				strconv.FormatBool(e.Image),
+				e.Alt,
+			}
+			if err := cw.Write(row); err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// printRedirects prints the redirect chain of each page that was redirected
+func printRedirects(res webcrawler.Result) {
+	pages := res.Pages()
+	redirected := make([]string, 0)
+	for u, p := range pages {
+		if len(p.Redirects) > 0 {
+			redirected = append(redirected, u)
+		}
+	}
+	if len(redirected) == 0 {
+		return
+	}
+	sort.Strings(redirected)
+
+	fmt.Printf("redirected %d pages:\n", len(redirected))
+	for _, u := range redirected {
+		p := pages[u]
+		chain := make([]string, 0, len(p.Redirects)+1)
+		for _, r := range p.Redirects {
+			chain = append(chain, fmt.Sprintf("%s (%d)", r.URL, r.StatusCode))
+		}
+		chain = append(chain, p.FinalURL)
+		line := strings.Join(chain, " -> ")
+		if p.Duplicate {
+			line += " [duplicate]"
+		}
+		fmt.Printf("  %s\n", line)
+	}
+}
+
+// printExcluded prints the URLs that were skipped by -include or -exclude, or seeds disallowed by robots.txt, and why
+func printExcluded(res webcrawler.Result) {
+	excluded := res.Excluded()
+	if len(excluded) == 0 {
+		return
+	}
+	urls := make([]string, 0, len(excluded))
+	for u := range excluded {
+		urls = append(urls, u)
+	}
+	sort.Strings(urls)
+
+	fmt.Printf("excluded %d URLs:\n", len(urls))
+	for _, u := range urls {
+		fmt.Printf("  %s: %s\n", u, excluded[u])
+	}
+}
+
+// printFailures prints a summary of the pages that failed to be crawled
+func printFailures(res webcrawler.Result) {
+	failed := res.FailedURLs()
+	if len(failed) == 0 {
+		return
+	}
+	errs := res.Errors()
+
+	counts := make(map[webcrawler.ErrorKind]int)
+	kinds := make([]webcrawler.ErrorKind, 0)
+	for _, u := range failed {
+		kind := errs[u].Kind
+		if counts[kind] == 0 {
+			kinds = append(kinds, kind)
+		}
+		counts[kind]++
+	}
+	sort.Slice(kinds, func(i, j int) bool { return kinds[i] < kinds[j] })
+
+	fmt.Printf("failed to crawl %d pages:\n", len(failed))
+	for _, kind := range kinds {
+		fmt.Printf("  %s: %d\n", kind, counts[kind])
+	}
+	for _, u := range failed {
+		fmt.Printf("  %s\n", errs[u])
+	}
+}
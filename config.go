@@ -0,0 +1,206 @@
+package web_crawler
+
+import (
+	"errors"
+	"fmt"
+	"github.com/jmwri/web-crawler/internal"
+	"net/url"
+	"time"
+)
+
+// ErrInvalidConfig is matched by every error returned from CrawlConfig.Validate
+var ErrInvalidConfig = errors.New("invalid crawl config")
+
+// ConfigError describes why a CrawlConfig field is invalid
+type ConfigError struct {
+	// Field is the name of the invalid CrawlConfig field
+	Field string
+	// Reason describes what is wrong with the field
+	Reason string
+}
+
+// Error describes the invalid field
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("%s: %s %s", ErrInvalidConfig, e.Field, e.Reason)
+}
+
+// Is allows ConfigError to be matched against ErrInvalidConfig with errors.Is
+func (e *ConfigError) Is(target error) bool {
+	return target == ErrInvalidConfig
+}
+
+// CrawlConfig defines the options for a single crawl
+type CrawlConfig struct {
+	// Seeds are the URLs to start crawling from. At least one is required. Each seed has its own depth and Scope, and
+	// they all share the same workers, so a URL that is reachable from several seeds is only crawled once. Page.Seed
+	// reports the seed that each page was reached from.
+	Seeds []*url.URL
+	// Scope restricts crawling to URLs that are within the scope of a seed. Defaults to ScopeAll.
+	Scope Scope
+	// IgnoreScheme treats http and https URLs as the same site when applying Scope
+	IgnoreScheme bool
+	// MaxDepth is the max depth that the crawler will go to. Set to 0 for no max depth.
+	MaxDepth int
+	// Workers is the amount of workers that will be used to crawl. Must be at least 1.
+	Workers int
+	// MaxPages is the max number of pages that will be crawled. Set to 0 for no max pages.
+	MaxPages int
+	// CheckResources requests links that aren't LinkKindNavigation, such as images and scripts, to check that they
+	// load. They are never scraped for links. Form actions are never requested, as submitting a form can have side
+	// effects. When false they are only recorded as edges.
+	CheckResources bool
+	// Include restricts crawling to discovered URLs that match at least one pattern. Empty includes everything.
+	Include []URLPattern
+	// Exclude skips discovered URLs that match any pattern. URLs skipped by Include or Exclude, and seeds
+	// disallowed by robots.txt, are reported by Result.Excluded.
+	Exclude []URLPattern
+	// MaxPagesPerHost is the max number of pages that will be crawled on each host. Set to 0 for no max.
+	MaxPagesPerHost int
+	// MaxBytes stops the crawl once this many bytes have been read from pages. Set to 0 for no max bytes.
+	MaxBytes int64
+	// MaxDuration is the max amount of time that the crawler will run for. Set to 0 for no max duration.
+	MaxDuration time.Duration
+	// GracePeriod is how long requests that are in flight when MaxDuration is reached, or Stop is closed, have to
+	// finish before they are cancelled. Cancelled requests are left out of the Result. Set to 0 to cancel them
+	// immediately.
+	GracePeriod time.Duration
+	// Stop stops the crawl when it is closed. No new requests are made, requests that are in flight have GracePeriod
+	// to finish, and the partial Result is returned with TruncatedStopped. Leave nil to only stop when ctx is done.
+	Stop <-chan struct{}
+	// Priority orders the URLs waiting to be crawled. Defaults to BreadthFirst.
+	Priority Priority
+	// MaxQueue is the max number of URLs that can wait to be crawled at once. URLs that are discovered while the
+	// queue is full are dropped, but can be queued again if they are found on another page. Defaults to
+	// DefaultMaxQueue.
+	MaxQueue int
+	// StrictDepth crawls one depth at a time, only starting on a depth once every page at the depth before has been
+	// crawled. Every page is then found at its shortest depth from a seed, so MaxDepth gives the same pages on every
+	// run, but workers can sit idle while the last pages at each depth are crawled.
+	StrictDepth bool
+	// StateDir is the directory that the crawl is checkpointed to, so that it can be resumed with LoadCheckpoint.
+	// It is created if it doesn't exist. Leave empty to disable checkpoints.
+	StateDir string
+	// CheckpointInterval is how often the crawl is checkpointed to StateDir. A checkpoint is always saved when the
+	// crawl finishes, including when it is cancelled. Defaults to DefaultCheckpointInterval.
+	CheckpointInterval time.Duration
+	// Resume continues the crawl from a Checkpoint. Pages in the Checkpoint aren't crawled again, and URLs that were
+	// waiting or in flight when it was saved are crawled first.
+	Resume *Checkpoint
+	// OnPage is called with a PageEvent for each page as soon as the crawler has finished with it.
+	// Calls are never made concurrently, so a slow OnPage will slow down the crawl.
+	OnPage func(PageEvent)
+}
+
+// TruncateReason describes why a crawl stopped before it ran out of URLs
+type TruncateReason = internal.TruncateReason
+
+const (
+	// NotTruncated is used when the crawl visited every URL it found
+	NotTruncated = internal.NotTruncated
+	// TruncatedMaxPages is used when MaxPages was reached
+	TruncatedMaxPages = internal.TruncatedMaxPages
+	// TruncatedMaxPagesPerHost is used when MaxPagesPerHost was reached for at least one host
+	TruncatedMaxPagesPerHost = internal.TruncatedMaxPagesPerHost
+	// TruncatedMaxBytes is used when MaxBytes was reached
+	TruncatedMaxBytes = internal.TruncatedMaxBytes
+	// TruncatedMaxDuration is used when MaxDuration was reached
+	TruncatedMaxDuration = internal.TruncatedMaxDuration
+	// TruncatedCancelled is used when the context of the crawl was done
+	TruncatedCancelled = internal.TruncatedCancelled
+	// TruncatedMaxQueue is used when URLs were dropped because MaxQueue was reached, and weren't found again later
+	TruncatedMaxQueue = internal.TruncatedMaxQueue
+	// TruncatedStopped is used when the crawl was stopped by closing Stop
+	TruncatedStopped = internal.TruncatedStopped
+)
+
+// PageEvent describes a single page that the crawler has finished with
+type PageEvent = internal.PageEvent
+
+// Validate returns a *ConfigError describing the first invalid field, or nil if the config is valid
+func (c CrawlConfig) Validate() error {
+	if len(c.Seeds) == 0 {
+		return &ConfigError{Field: "Seeds", Reason: "must contain at least one URL"}
+	}
+	for i, seed := range c.Seeds {
+		if err := validateSeed(seed); err != nil {
+			return &ConfigError{Field: fmt.Sprintf("Seeds[%d]", i), Reason: err.Error()}
+		}
+	}
+	if c.Scope < ScopeAll || c.Scope > ScopeRegistrableDomain {
+		return &ConfigError{Field: "Scope", Reason: "must be a known Scope"}
+	}
+	if c.MaxDepth < 0 {
+		return &ConfigError{Field: "MaxDepth", Reason: "must not be negative"}
+	}
+	if c.Workers < 1 {
+		return &ConfigError{Field: "Workers", Reason: "must be at least 1"}
+	}
+	if c.MaxPages < 0 {
+		return &ConfigError{Field: "MaxPages", Reason: "must not be negative"}
+	}
+	if c.MaxPagesPerHost < 0 {
+		return &ConfigError{Field: "MaxPagesPerHost", Reason: "must not be negative"}
+	}
+	if c.MaxBytes < 0 {
+		return &ConfigError{Field: "MaxBytes", Reason: "must not be negative"}
+	}
+	if c.MaxQueue < 0 {
+		return &ConfigError{Field: "MaxQueue", Reason: "must not be negative"}
+	}
+	if c.CheckpointInterval < 0 {
+		return &ConfigError{Field: "CheckpointInterval", Reason: "must not be negative"}
+	}
+	if c.MaxDuration < 0 {
+		return &ConfigError{Field: "MaxDuration", Reason: "must not be negative"}
+	}
+	if c.GracePeriod < 0 {
+		return &ConfigError{Field: "GracePeriod", Reason: "must not be negative"}
+	}
+	return nil
+}
+
+// validateSeed checks that the seed is an absolute HTTP URL
+func validateSeed(seed *url.URL) error {
+	if seed == nil {
+		return errors.New("must not be nil")
+	}
+	if seed.Scheme != "http" && seed.Scheme != "https" {
+		return fmt.Errorf("must have an http or https scheme, got '%s'", seed.String())
+	}
+	if seed.Host == "" {
+		return fmt.Errorf("must have a host, got '%s'", seed.String())
+	}
+	return nil
+}
+
+// crawlOptions converts the config to internal.CrawlOptions
+func (c CrawlConfig) crawlOptions() internal.CrawlOptions {
+	var priority internal.PriorityFunc
+	if c.Priority != nil {
+		priority = internal.PriorityFunc(c.Priority)
+	}
+	return internal.CrawlOptions{
+		Target:             c.Seeds[0],
+		Seeds:              c.Seeds[1:],
+		Scope:              c.Scope,
+		IgnoreScheme:       c.IgnoreScheme,
+		MaxDepth:           c.MaxDepth,
+		Workers:            c.Workers,
+		MaxPages:           c.MaxPages,
+		OnPage:             c.OnPage,
+		CheckResources:     c.CheckResources,
+		MaxPagesPerHost:    c.MaxPagesPerHost,
+		MaxBytes:           c.MaxBytes,
+		MaxDuration:        c.MaxDuration,
+		GracePeriod:        c.GracePeriod,
+		Stop:               c.Stop,
+		Include:            c.Include,
+		Exclude:            c.Exclude,
+		Priority:           priority,
+		MaxQueue:           c.MaxQueue,
+		StrictDepth:        c.StrictDepth,
+		StateDir:           c.StateDir,
+		CheckpointInterval: c.CheckpointInterval,
+		Resume:             c.Resume,
+	}
+}
@@ -0,0 +1,114 @@
+package web_crawler_test
+
+import (
+	"errors"
+	webcrawler "github.com/jmwri/web-crawler"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestCrawlConfig_Validate(t *testing.T) {
+	seed := &url.URL{Scheme: "https", Host: "localhost"}
+	tests := []struct {
+		name      string
+		cfg       webcrawler.CrawlConfig
+		wantField string
+	}{
+		{
+			name: "valid",
+			cfg:  webcrawler.CrawlConfig{Seeds: []*url.URL{seed}, Workers: 1},
+		},
+		{
+			name:      "no seeds",
+			cfg:       webcrawler.CrawlConfig{Workers: 1},
+			wantField: "Seeds",
+		},
+		{
+			name:      "nil seed",
+			cfg:       webcrawler.CrawlConfig{Seeds: []*url.URL{seed, nil}, Workers: 1},
+			wantField: "Seeds[1]",
+		},
+		{
+			name:      "relative seed",
+			cfg:       webcrawler.CrawlConfig{Seeds: []*url.URL{{Path: "index.html"}}, Workers: 1},
+			wantField: "Seeds[0]",
+		},
+		{
+			name:      "non http seed",
+			cfg:       webcrawler.CrawlConfig{Seeds: []*url.URL{{Scheme: "mailto", Opaque: "test@localhost"}}, Workers: 1},
+			wantField: "Seeds[0]",
+		},
+		{
+			name:      "negative max depth",
+			cfg:       webcrawler.CrawlConfig{Seeds: []*url.URL{seed}, Workers: 1, MaxDepth: -1},
+			wantField: "MaxDepth",
+		},
+		{
+			name:      "no workers",
+			cfg:       webcrawler.CrawlConfig{Seeds: []*url.URL{seed}},
+			wantField: "Workers",
+		},
+		{
+			name:      "negative max pages",
+			cfg:       webcrawler.CrawlConfig{Seeds: []*url.URL{seed}, Workers: 1, MaxPages: -1},
+			wantField: "MaxPages",
+		},
+		{
+			name:      "unknown scope",
+			cfg:       webcrawler.CrawlConfig{Seeds: []*url.URL{seed}, Workers: 1, Scope: webcrawler.Scope(-1)},
+			wantField: "Scope",
+		},
+		{
+			name:      "negative max pages per host",
+			cfg:       webcrawler.CrawlConfig{Seeds: []*url.URL{seed}, Workers: 1, MaxPagesPerHost: -1},
+			wantField: "MaxPagesPerHost",
+		},
+		{
+			name:      "negative max bytes",
+			cfg:       webcrawler.CrawlConfig{Seeds: []*url.URL{seed}, Workers: 1, MaxBytes: -1},
+			wantField: "MaxBytes",
+		},
+		{
+			name:      "negative max queue",
+			cfg:       webcrawler.CrawlConfig{Seeds: []*url.URL{seed}, Workers: 1, MaxQueue: -1},
+			wantField: "MaxQueue",
+		},
+		{
+			name:      "negative checkpoint interval",
+			cfg:       webcrawler.CrawlConfig{Seeds: []*url.URL{seed}, Workers: 1, CheckpointInterval: -time.Second},
+			wantField: "CheckpointInterval",
+		},
+		{
+			name:      "negative max duration",
+			cfg:       webcrawler.CrawlConfig{Seeds: []*url.URL{seed}, Workers: 1, MaxDuration: -time.Second},
+			wantField: "MaxDuration",
+		},
+		{
+			name:      "negative grace period",
+			cfg:       webcrawler.CrawlConfig{Seeds: []*url.URL{seed}, Workers: 1, GracePeriod: -time.Second},
+			wantField: "GracePeriod",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.Validate()
+			if tt.wantField == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v, want nil", err)
+				}
+				return
+			}
+			if !errors.Is(err, webcrawler.ErrInvalidConfig) {
+				t.Fatalf("Validate() error = %v, want %v", err, webcrawler.ErrInvalidConfig)
+			}
+			var cfgErr *webcrawler.ConfigError
+			if !errors.As(err, &cfgErr) {
+				t.Fatalf("Validate() error = %T, want *ConfigError", err)
+			}
+			if cfgErr.Field != tt.wantField {
+				t.Errorf("Validate() field = %s, want %s", cfgErr.Field, tt.wantField)
+			}
+		})
+	}
+}
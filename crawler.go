@@ -1,8 +1,8 @@
 package web_crawler
 
 import (
+	"context"
 	"github.com/jmwri/web-crawler/internal"
-	"net/http"
 	"net/url"
 )
 
@@ -10,28 +10,137 @@ import (
 type Crawler interface {
 	// Crawl according to the specified options
 	Crawl(target *url.URL, sameDomain bool, maxDepth int, workers int) (Result, error)
+	// CrawlContext crawls according to the specified options until finished or ctx is done.
+	// If ctx is done first, the partial Result is returned along with the context error.
+	CrawlContext(ctx context.Context, target *url.URL, sameDomain bool, maxDepth int, workers int) (Result, error)
+	// CrawlWithConfig crawls according to the given config until finished or ctx is done.
+	// An invalid config returns an error matching ErrInvalidConfig, and a StateDir that can't be created returns an
+	// error matching ErrStateDir, without crawling.
+	CrawlWithConfig(ctx context.Context, cfg CrawlConfig) (Result, error)
 }
 
 // Result is the output of Crawler
 type Result interface {
 	// Target is the URL that the crawler started on
 	Target() *url.URL
-	// SameDomain specifies if the crawler was limited to the same domain
+	// Seeds are all of the URLs that the crawler started on, including Target
+	Seeds() []*url.URL
+	// SameDomain specifies if the crawler was limited to the same domain, with any Scope other than ScopeAll
 	SameDomain() bool
+	// Scope returns the Scope that the crawler was limited to
+	Scope() Scope
 	// MaxDepth returns the depth that the crawler was limited to
 	MaxDepth() int
 	// URLs returns a map of URLs that the crawler visited, and a list of URLs found on that page
 	URLs() map[string][]string
+	// Pages returns a map of URLs that the crawler visited, and the Page crawled at that URL
+	Pages() map[string]Page
+	// Excluded returns a map of discovered URLs that were skipped by CrawlConfig.Include or CrawlConfig.Exclude, and
+	// seeds that were disallowed by robots.txt, along with the reason why
+	Excluded() map[string]string
+	// Truncated returns true if the crawl stopped before it visited every URL it found, because a budget in
+	// CrawlConfig ran out or the crawl was cancelled
+	Truncated() bool
+	// TruncateReason returns why the crawl stopped early, or NotTruncated
+	TruncateReason() TruncateReason
+	// QueueStats returns how the queue of URLs waiting to be crawled was used
+	QueueStats() QueueStats
+	// Sitemaps returns the sitemap URLs listed in the robots.txt files of the hosts that were crawled. It is empty if
+	// robots.txt was ignored.
+	Sitemaps() []string
+	// Errors returns a map of URLs that the crawler failed to crawl, and the reason why
+	Errors() map[string]*PageError
+	// FailedURLs returns a sorted list of URLs that the crawler failed to crawl
+	FailedURLs() []string
 }
 
+// Page describes a single page that the crawler visited
+type Page = internal.Page
+
+// Redirect is a single hop in the redirect chain of a Page
+type Redirect = internal.Redirect
+
+// Edge is a link from a Page to another URL
+type Edge = internal.Edge
+
+// LinkKind describes the type of resource that an Edge points to
+type LinkKind = internal.LinkKind
+
+const (
+	// LinkKindNavigation is a link to another page, from an <a> or <area>
+	LinkKindNavigation = internal.LinkKindNavigation
+	// LinkKindStylesheet is a <link rel="stylesheet">
+	LinkKindStylesheet = internal.LinkKindStylesheet
+	// LinkKindImage is an <img>, a <source> in a <picture>, a <video> poster or a <link rel="icon">
+	LinkKindImage = internal.LinkKindImage
+	// LinkKindScript is a <script src>
+	LinkKindScript = internal.LinkKindScript
+	// LinkKindFrame is an <iframe> or <frame>
+	LinkKindFrame = internal.LinkKindFrame
+	// LinkKindForm is the action of a <form>
+	LinkKindForm = internal.LinkKindForm
+	// LinkKindMedia is a <video>, <audio>, or one of their <source> or <track> elements
+	LinkKindMedia = internal.LinkKindMedia
+	// LinkKindOther is any other <link>, ie rel="canonical" or rel="preload"
+	LinkKindOther = internal.LinkKindOther
+)
+
 // DefaultCrawler is the default Crawler
-var DefaultCrawler Crawler
+var DefaultCrawler = NewCrawler()
 
-func init() {
-	loader := internal.NewHTTPGetLoader(http.DefaultClient)
-	DefaultCrawler = crawler{
-		loader:    internal.LoaderWithRetry(loader.Load, internal.SimpleBackoff, 5),
-		extractor: internal.HtmlTokenExtractor,
+// NewCrawler returns a Crawler configured with the given options.
+// Any options that aren't given fall back to the same defaults as DefaultCrawler.
+func NewCrawler(opts ...Option) Crawler {
+	cfg := defaultConfig()
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	loader := internal.LoaderFunc(cfg.loader)
+	robotsLoader := loader
+	if loader == nil {
+		httpOpts := []internal.HTTPGetLoaderOption{
+			internal.WithUserAgent(cfg.userAgent),
+			internal.WithContentTypes(cfg.contentTypes...),
+		}
+		if cfg.headProbe {
+			httpOpts = append(httpOpts, internal.WithHeadProbe())
+		}
+		httpLoader := internal.NewHTTPGetLoader(cfg.client, httpOpts...)
+		loader = httpLoader.Load
+		// robots.txt is plain text, so it needs a loader that allows any content type
+		robotsHTTPLoader := internal.NewHTTPGetLoader(cfg.client, internal.WithUserAgent(cfg.userAgent), internal.WithContentTypes())
+		robotsLoader = robotsHTTPLoader.Load
+	}
+	// The limiter is always used, as robots.txt may set a crawl delay
+	limiter := internal.NewHostLimiter(cfg.rate, cfg.delay, cfg.hostConcurrency)
+	loader = internal.LoaderWithHostLimiter(loader, limiter)
+	robotsLoader = internal.LoaderWithHostLimiter(robotsLoader, limiter)
+
+	filters := make([]internal.URLFilterFunc, len(cfg.filters))
+	for i, f := range cfg.filters {
+		filters[i] = internal.URLFilterFunc(f)
+	}
+	modifiers := make([]internal.URLModifyFunc, len(cfg.modifiers))
+	for i, m := range cfg.modifiers {
+		modifiers[i] = internal.URLModifyFunc(m)
+	}
+
+	return crawler{
+		loader: internal.LoaderWithRetryPolicy(loader, internal.RetryPolicy{
+			MaxAttempts:   cfg.attempts,
+			Backoff:       internal.BackoffFunc(cfg.backoff),
+			MaxRetryAfter: cfg.maxRetryAfter,
+			Retryable:     cfg.retryable,
+		}),
+		extractor:    internal.ExtractorFunc(cfg.extractor),
+		filters:      filters,
+		modifiers:    modifiers,
+		robotsLoader: robotsLoader,
+		userAgent:    cfg.userAgent,
+		limiter:      limiter,
+		ignoreRobots: cfg.ignoreRobots,
+		robotsExempt: cfg.robotsExempt,
 	}
 }
 
@@ -39,10 +148,47 @@ func init() {
 type crawler struct {
 	loader    internal.LoaderFunc
 	extractor internal.ExtractorFunc
+	filters   []internal.URLFilterFunc
+	modifiers []internal.URLModifyFunc
+	// robotsLoader loads robots.txt files. It doesn't retry, as a missing robots.txt is common.
+	robotsLoader internal.LoaderFunc
+	userAgent    string
+	limiter      *internal.HostLimiter
+	ignoreRobots bool
+	robotsExempt []string
 }
 
 // Crawl according to the specified options
 func (c crawler) Crawl(target *url.URL, sameDomain bool, maxDepth int, workers int) (Result, error) {
+	return c.CrawlContext(context.Background(), target, sameDomain, maxDepth, workers)
+}
+
+// CrawlContext crawls according to the specified options until finished or ctx is done
+func (c crawler) CrawlContext(ctx context.Context, target *url.URL, sameDomain bool, maxDepth int, workers int) (Result, error) {
+	// Sanitise the input in the same way as before CrawlConfig existed
 	o := internal.NewCrawlOptions(target, sameDomain, maxDepth, workers)
-	return internal.Crawl(c.loader, c.extractor, o)
+	return c.CrawlWithConfig(ctx, CrawlConfig{
+		Seeds:    []*url.URL{o.Target},
+		Scope:    o.Scope,
+		MaxDepth: o.MaxDepth,
+		Workers:  o.Workers,
+	})
+}
+
+// CrawlWithConfig crawls according to the given config until finished or ctx is done
+func (c crawler) CrawlWithConfig(ctx context.Context, cfg CrawlConfig) (Result, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	o := cfg.crawlOptions()
+	o.Filters = c.filters
+	o.Modifiers = c.modifiers
+	o.UserAgent = c.userAgent
+	if !c.ignoreRobots {
+		// robots.txt is cached for a single crawl, so that long lived crawlers see changes
+		o.Robots = internal.NewRobotsCache(c.robotsLoader, c.userAgent, c.limiter, c.robotsExempt...)
+	}
+
+	return internal.CrawlContext(ctx, c.loader, c.extractor, o)
 }
@@ -0,0 +1,189 @@
+package web_crawler_test
+
+import (
+	"context"
+	"errors"
+	webcrawler "github.com/jmwri/web-crawler"
+	"io/ioutil"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testPages is a small site that can be served by testLoader
+var testPages = map[string]string{
+	"https://localhost/index":   `<a href="/about/">About</a><a href="/private">Private</a>`,
+	"https://localhost/about":   `<a href="/index">Home</a>`,
+	"https://localhost/private": `<a href="/index">Home</a>`,
+}
+
+// testLoader serves testPages, and fails for anything else
+func testLoader(ctx context.Context, p string) (*webcrawler.Response, error) {
+	page, ok := testPages[p]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return &webcrawler.Response{Body: ioutil.NopCloser(strings.NewReader(page))}, nil
+}
+
+func TestNewCrawler(t *testing.T) {
+	noPrivate := func(urls []*url.URL) []*url.URL {
+		filtered := make([]*url.URL, 0)
+		for _, u := range urls {
+			if u.Path != "/private" {
+				filtered = append(filtered, u)
+			}
+		}
+		return filtered
+	}
+	tests := []struct {
+		name     string
+		opts     []webcrawler.Option
+		wantURLs map[string][]string
+	}{
+		{
+			name: "custom loader",
+			opts: []webcrawler.Option{
+				webcrawler.WithLoader(testLoader),
+			},
+			wantURLs: map[string][]string{
+				"https://localhost/index":   {"https://localhost/about", "https://localhost/private"},
+				"https://localhost/about":   {"https://localhost/index"},
+				"https://localhost/private": {"https://localhost/index"},
+			},
+		},
+		{
+			name: "custom filter",
+			opts: []webcrawler.Option{
+				webcrawler.WithLoader(testLoader),
+				webcrawler.WithFilters(noPrivate),
+			},
+			wantURLs: map[string][]string{
+				"https://localhost/index": {"https://localhost/about"},
+				"https://localhost/about": {"https://localhost/index"},
+			},
+		},
+		{
+			name: "custom modifier",
+			opts: []webcrawler.Option{
+				webcrawler.WithLoader(testLoader),
+				webcrawler.WithModifiers(func(u *url.URL) { u.Path = "/index" }),
+			},
+			wantURLs: map[string][]string{
+				"https://localhost/index": {"https://localhost/index"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := webcrawler.NewCrawler(tt.opts...)
+			got, err := c.Crawl(&url.URL{Scheme: "https", Host: "localhost", Path: "/index"}, true, 0, 1)
+			if err != nil {
+				t.Fatalf("Crawl() error = %v", err)
+			}
+			if !reflect.DeepEqual(got.URLs(), tt.wantURLs) {
+				t.Errorf("Crawl().URLs() got = %v, want %v", got.URLs(), tt.wantURLs)
+			}
+		})
+	}
+}
+
+func TestNewCrawler_Retries(t *testing.T) {
+	var loaderCalls int
+	failingLoader := func(ctx context.Context, p string) (*webcrawler.Response, error) {
+		loaderCalls++
+		return nil, errors.New("always fail")
+	}
+	noBackoff := func(attempt int) time.Duration { return 0 }
+
+	c := webcrawler.NewCrawler(
+		webcrawler.WithLoader(failingLoader),
+		webcrawler.WithBackoff(noBackoff),
+		webcrawler.WithMaxAttempts(3),
+		webcrawler.WithIgnoreRobots(),
+	)
+	_, err := c.Crawl(&url.URL{Scheme: "https", Host: "localhost", Path: "/index"}, true, 0, 1)
+	if err != nil {
+		t.Fatalf("Crawl() error = %v", err)
+	}
+	if loaderCalls != 3 {
+		t.Errorf("expecting %d calls, got %d", 3, loaderCalls)
+	}
+}
+
+func TestCrawler_CrawlWithConfig(t *testing.T) {
+	c := webcrawler.NewCrawler(webcrawler.WithLoader(testLoader))
+	tests := []struct {
+		name      string
+		cfg       webcrawler.CrawlConfig
+		wantPages int
+		wantErr   error
+	}{
+		{
+			name: "multiple seeds",
+			cfg: webcrawler.CrawlConfig{
+				Seeds: []*url.URL{
+					{Scheme: "https", Host: "localhost", Path: "/about"},
+					{Scheme: "https", Host: "localhost", Path: "/private"},
+				},
+				Scope:    webcrawler.ScopeHost,
+				MaxDepth: 1,
+				Workers:  1,
+			},
+			wantPages: 2,
+		},
+		{
+			name: "max pages",
+			cfg: webcrawler.CrawlConfig{
+				Seeds:    []*url.URL{{Scheme: "https", Host: "localhost", Path: "/index"}},
+				Scope:    webcrawler.ScopeHost,
+				Workers:  1,
+				MaxPages: 2,
+			},
+			wantPages: 2,
+		},
+		{
+			name: "invalid",
+			cfg: webcrawler.CrawlConfig{
+				Seeds: []*url.URL{{Scheme: "https", Host: "localhost", Path: "/index"}},
+			},
+			wantErr: webcrawler.ErrInvalidConfig,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := c.CrawlWithConfig(context.Background(), tt.cfg)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CrawlWithConfig() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if len(got.URLs()) != tt.wantPages {
+				t.Errorf("CrawlWithConfig().URLs() got = %v, want %d pages", got.URLs(), tt.wantPages)
+			}
+		})
+	}
+}
+
+func TestCrawler_CrawlWithConfig_MaxDuration(t *testing.T) {
+	slowLoader := func(ctx context.Context, p string) (*webcrawler.Response, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	c := webcrawler.NewCrawler(webcrawler.WithLoader(slowLoader), webcrawler.WithMaxAttempts(1))
+
+	got, err := c.CrawlWithConfig(context.Background(), webcrawler.CrawlConfig{
+		Seeds:       []*url.URL{{Scheme: "https", Host: "localhost", Path: "/index"}},
+		Workers:     1,
+		MaxDuration: time.Millisecond * 50,
+	})
+	if err != nil {
+		t.Fatalf("CrawlWithConfig() error = %v, want nil", err)
+	}
+	if got.TruncateReason() != webcrawler.TruncatedMaxDuration {
+		t.Errorf("CrawlWithConfig().TruncateReason() = %s, want %s", got.TruncateReason(), webcrawler.TruncatedMaxDuration)
+	}
+}
@@ -0,0 +1,32 @@
+package web_crawler
+
+import "github.com/jmwri/web-crawler/internal"
+
+// ErrorKind categorises why a page could not be crawled
+type ErrorKind = internal.ErrorKind
+
+const (
+	// ErrorKindLoad is used when the page could not be loaded for a reason that isn't otherwise categorised
+	ErrorKindLoad = internal.ErrorKindLoad
+	// ErrorKindNetwork is used when the page could not be loaded because of a network error
+	ErrorKindNetwork = internal.ErrorKindNetwork
+	// ErrorKindTimeout is used when loading the page timed out
+	ErrorKindTimeout = internal.ErrorKindTimeout
+	// ErrorKindCancelled is used when the crawl was cancelled while the page was loading
+	ErrorKindCancelled = internal.ErrorKindCancelled
+	// ErrorKindHTTPStatus is used when the page responded with an unsuccessful HTTP status
+	ErrorKindHTTPStatus = internal.ErrorKindHTTPStatus
+	// ErrorKindExtract is used when links could not be extracted from the page
+	ErrorKindExtract = internal.ErrorKindExtract
+	// ErrorKindNonHTML is used when the page was skipped because it isn't HTML
+	ErrorKindNonHTML = internal.ErrorKindNonHTML
+)
+
+// PageError describes why a page could not be crawled
+type PageError = internal.PageError
+
+// StatusError is the underlying error of a PageError with ErrorKindHTTPStatus when using the default loader
+type StatusError = internal.StatusError
+
+// ContentTypeError is the underlying error of a PageError with ErrorKindNonHTML when using the default loader
+type ContentTypeError = internal.ContentTypeError
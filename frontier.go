@@ -0,0 +1,29 @@
+package web_crawler
+
+import "github.com/jmwri/web-crawler/internal"
+
+// DefaultMaxQueue is the max number of URLs that can wait to be crawled at once unless CrawlConfig.MaxQueue is set
+const DefaultMaxQueue = internal.DefaultMaxQueue
+
+// QueuedRequest describes a URL that is waiting to be crawled
+type QueuedRequest = internal.QueuedRequest
+
+// QueueStats describes how the queue of URLs waiting to be crawled was used
+type QueueStats = internal.QueueStats
+
+// Priority returns the priority of a queued URL. URLs with a lower priority are crawled first, and URLs with the same
+// priority are crawled in the order that they were queued. It is called once for each URL as it is queued, and never
+// concurrently.
+type Priority func(r QueuedRequest) float64
+
+// BreadthFirst is a Priority that crawls URLs in order of depth, so that shallow pages are crawled before deep ones.
+// It is used unless CrawlConfig.Priority is set.
+func BreadthFirst(r QueuedRequest) float64 {
+	return internal.BreadthFirstPriority(r)
+}
+
+// HostRoundRobin is a Priority that takes turns between hosts, so that one large host doesn't hold up the rest of the
+// crawl
+func HostRoundRobin(r QueuedRequest) float64 {
+	return internal.HostRoundRobinPriority(r)
+}
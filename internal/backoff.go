@@ -1,6 +1,10 @@
 package internal
 
-import "time"
+import (
+	"math"
+	"math/rand"
+	"time"
+)
 
 // BackoffFunc returns the duration to sleep before making an attempt
 type BackoffFunc func(attempt int) time.Duration
@@ -10,3 +14,35 @@ func SimpleBackoff(attempt int) time.Duration {
 	increment := time.Millisecond * 500
 	return time.Duration(attempt-1) * increment
 }
+
+// ExponentialBackoff returns a BackoffFunc that doubles the duration for each attempt, starting from base.
+// The duration is capped at max, unless max is 0. jitter is the fraction of the duration, between 0 and 1, that is
+// randomly removed so that retries from many workers are spread out.
+func ExponentialBackoff(base time.Duration, max time.Duration, jitter float64) BackoffFunc {
+	if jitter < 0 {
+		jitter = 0
+	}
+	if jitter > 1 {
+		jitter = 1
+	}
+	return func(attempt int) time.Duration {
+		if attempt <= 1 {
+			return 0
+		}
+		d := base
+		for i := 2; i < attempt; i++ {
+			// Stop doubling once we reach the cap, or before the duration overflows
+			if (max > 0 && d >= max) || d > math.MaxInt64/2 {
+				break
+			}
+			d *= 2
+		}
+		if max > 0 && d > max {
+			d = max
+		}
+		if jitter > 0 {
+			d -= time.Duration(rand.Float64() * jitter * float64(d))
+		}
+		return d
+	}
+}
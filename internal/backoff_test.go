@@ -39,3 +39,49 @@ func TestSimpleBackoff(t *testing.T) {
 		})
 	}
 }
+
+func TestExponentialBackoff(t *testing.T) {
+	type params struct {
+		base time.Duration
+		max  time.Duration
+	}
+	tests := []struct {
+		name    string
+		params  params
+		attempt int
+		want    time.Duration
+	}{
+		{name: "attempt 1", params: params{base: time.Millisecond * 100}, attempt: 1, want: 0},
+		{name: "attempt 2", params: params{base: time.Millisecond * 100}, attempt: 2, want: time.Millisecond * 100},
+		{name: "attempt 3", params: params{base: time.Millisecond * 100}, attempt: 3, want: time.Millisecond * 200},
+		{name: "attempt 5", params: params{base: time.Millisecond * 100}, attempt: 5, want: time.Millisecond * 800},
+		{name: "capped", params: params{base: time.Millisecond * 100, max: time.Millisecond * 300}, attempt: 5, want: time.Millisecond * 300},
+		{name: "no overflow", params: params{base: time.Second}, attempt: 1000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := internal.ExponentialBackoff(tt.params.base, tt.params.max, 0)
+			got := b(tt.attempt)
+			if tt.want == 0 && tt.attempt > 1 {
+				// Only checking that the duration hasn't overflowed
+				if got <= 0 {
+					t.Errorf("ExponentialBackoff() = %v, want > 0", got)
+				}
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ExponentialBackoff() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExponentialBackoff_Jitter(t *testing.T) {
+	b := internal.ExponentialBackoff(time.Second, 0, 0.5)
+	for i := 0; i < 100; i++ {
+		got := b(3)
+		if got < time.Second || got > time.Second*2 {
+			t.Fatalf("ExponentialBackoff() = %v, want from %v to %v", got, time.Second, time.Second*2)
+		}
+	}
+}
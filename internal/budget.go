@@ -0,0 +1,173 @@
+package internal
+
+import (
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// TruncateReason describes why a crawl stopped before it ran out of URLs
+type TruncateReason int
+
+const (
+	// NotTruncated is used when the crawl visited every URL it found
+	NotTruncated TruncateReason = iota
+	// TruncatedMaxPages is used when MaxPages was reached
+	TruncatedMaxPages
+	// TruncatedMaxPagesPerHost is used when MaxPagesPerHost was reached for at least one host
+	TruncatedMaxPagesPerHost
+	// TruncatedMaxBytes is used when MaxBytes was reached
+	TruncatedMaxBytes
+	// TruncatedMaxDuration is used when MaxDuration was reached
+	TruncatedMaxDuration
+	// TruncatedCancelled is used when the context of the crawl was done
+	TruncatedCancelled
+	// TruncatedMaxQueue is used when URLs were dropped because MaxQueue was reached, and weren't found again later
+	TruncatedMaxQueue
+	// TruncatedStopped is used when the crawl was stopped by closing Stop
+	TruncatedStopped
+)
+
+// String describes the TruncateReason
+func (r TruncateReason) String() string {
+	switch r {
+	case NotTruncated:
+		return "not truncated"
+	case TruncatedMaxPages:
+		return "max pages reached"
+	case TruncatedMaxPagesPerHost:
+		return "max pages per host reached"
+	case TruncatedMaxBytes:
+		return "max bytes reached"
+	case TruncatedMaxDuration:
+		return "max duration reached"
+	case TruncatedCancelled:
+		return "cancelled"
+	case TruncatedMaxQueue:
+		return "max queue reached"
+	case TruncatedStopped:
+		return "stopped"
+	default:
+		return "unknown"
+	}
+}
+
+// budget tracks the pages and bytes used by a crawl against its limits, and why the crawl was truncated
+type budget struct {
+	// maxPages, maxPagesPerHost and maxBytes are the limits. 0 means no limit.
+	maxPages        int
+	maxPagesPerHost int
+	maxBytes        int64
+	// pages is the number of pages that have been claimed, and hostPages is the same per host
+	pages     int
+	hostPages map[string]int
+	// bytes is the number of bytes read so far
+	bytes int64
+	// stopped is true once the crawl shouldn't make any more requests
+	stopped bool
+	// reason is the first reason that the crawl was truncated
+	reason TruncateReason
+	// mu is an internal mutex to ensure routine safe access of the fields above
+	mu *sync.Mutex
+}
+
+// newBudget returns a budget with the limits in the given options
+func newBudget(o CrawlOptions) *budget {
+	return &budget{
+		maxPages:        o.MaxPages,
+		maxPagesPerHost: o.MaxPagesPerHost,
+		maxBytes:        o.MaxBytes,
+		hostPages:       make(map[string]int),
+		mu:              &sync.Mutex{},
+	}
+}
+
+// claim uses up a page for the URL, returning false if there are none left
+func (b *budget) claim(u *url.URL) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.stopped {
+		return false
+	}
+	return b.claimPage(u)
+}
+
+// keep uses up a page for the URL even if the crawl has stopped, returning false if there are none left. It is used
+// for URLs that are kept so that they can be crawled when the crawl is resumed.
+func (b *budget) keep(u *url.URL) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.claimPage(u)
+}
+
+// claimPage uses up a page for the URL, returning false if there are none left. b.mu must be held.
+func (b *budget) claimPage(u *url.URL) bool {
+	if b.maxPages > 0 && b.pages >= b.maxPages {
+		b.truncate(TruncatedMaxPages)
+		return false
+	}
+	host := strings.ToLower(u.Host)
+	if b.maxPagesPerHost > 0 && b.hostPages[host] >= b.maxPagesPerHost {
+		b.truncate(TruncatedMaxPagesPerHost)
+		return false
+	}
+	b.pages++
+	b.hostPages[host]++
+	return true
+}
+
+// use uses up a page for the URL regardless of the limits, for pages that were claimed before a crawl was resumed
+func (b *budget) use(u *url.URL) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.pages++
+	b.hostPages[strings.ToLower(u.Host)]++
+}
+
+// release gives back the page that was claimed for the URL
+func (b *budget) release(u *url.URL) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.pages--
+	b.hostPages[strings.ToLower(u.Host)]--
+}
+
+// addBytes uses up n bytes, stopping the crawl if there are none left
+func (b *budget) addBytes(n int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.bytes += n
+	if b.maxBytes > 0 && b.bytes >= b.maxBytes && !b.stopped {
+		b.stopped = true
+		b.truncate(TruncatedMaxBytes)
+	}
+}
+
+// stop the crawl for the given reason, so that no more requests are made
+func (b *budget) stop(reason TruncateReason) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.stopped = true
+	b.truncate(reason)
+}
+
+// isStopped returns true if no more requests should be made
+func (b *budget) isStopped() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.stopped
+}
+
+// truncateReason returns the first reason that the crawl was truncated
+func (b *budget) truncateReason() TruncateReason {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.reason
+}
+
+// truncate records the reason, unless the crawl was already truncated. b.mu must be held.
+func (b *budget) truncate(reason TruncateReason) {
+	if b.reason == NotTruncated {
+		b.reason = reason
+	}
+}
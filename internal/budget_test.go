@@ -0,0 +1,186 @@
+package internal_test
+
+import (
+	"context"
+	"github.com/jmwri/web-crawler/internal"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCrawl_Budgets(t *testing.T) {
+	target := &url.URL{Scheme: "https", Host: "localhost", Path: "index.html"}
+	tests := []struct {
+		name       string
+		o          func(o *internal.CrawlOptions)
+		wantPages  int
+		wantReason internal.TruncateReason
+	}{
+		{
+			name:       "no budget",
+			o:          func(o *internal.CrawlOptions) {},
+			wantPages:  3,
+			wantReason: internal.NotTruncated,
+		},
+		{
+			name:       "budget not reached",
+			o:          func(o *internal.CrawlOptions) { o.MaxPages = 3 },
+			wantPages:  3,
+			wantReason: internal.NotTruncated,
+		},
+		{
+			name:       "max pages",
+			o:          func(o *internal.CrawlOptions) { o.MaxPages = 2 },
+			wantPages:  2,
+			wantReason: internal.TruncatedMaxPages,
+		},
+		{
+			name: "max pages per host",
+			o: func(o *internal.CrawlOptions) {
+				o.Scope = internal.ScopeAll
+				o.MaxDepth = 2
+				o.MaxPagesPerHost = 1
+			},
+			// index.html and github.com, as test.com is only linked from about.html
+			wantPages:  2,
+			wantReason: internal.TruncatedMaxPagesPerHost,
+		},
+		{
+			name:       "max bytes",
+			o:          func(o *internal.CrawlOptions) { o.MaxBytes = 1 },
+			wantPages:  1,
+			wantReason: internal.TruncatedMaxBytes,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := internal.NewCrawlOptions(target, true, 0, 1)
+			tt.o(&o)
+
+			got, err := internal.Crawl(testFileLoader, internal.HtmlTokenExtractor, o)
+			if err != nil {
+				t.Fatalf("Crawl() error = %v", err)
+			}
+			if len(got.Pages()) != tt.wantPages {
+				t.Errorf("Crawl().Pages() got %d pages, want %d: %v", len(got.Pages()), tt.wantPages, got.URLs())
+			}
+			if got.TruncateReason() != tt.wantReason {
+				t.Errorf("Crawl().TruncateReason() = %s, want %s", got.TruncateReason(), tt.wantReason)
+			}
+			if got.Truncated() != (tt.wantReason != internal.NotTruncated) {
+				t.Errorf("Crawl().Truncated() = %v, want %v", got.Truncated(), tt.wantReason != internal.NotTruncated)
+			}
+		})
+	}
+}
+
+func TestCrawl_MaxDuration(t *testing.T) {
+	target := &url.URL{Scheme: "https", Host: "localhost", Path: "index.html"}
+	// Serve the index page straight away, and every other page after a delay
+	var slowLoader internal.LoaderFunc = func(ctx context.Context, p string) (*internal.Response, error) {
+		if p != target.String() {
+			select {
+			case <-time.After(time.Millisecond * 100):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+		return testFileLoader(ctx, p)
+	}
+	tests := []struct {
+		name        string
+		gracePeriod time.Duration
+		wantPages   int
+	}{
+		{
+			name:        "in flight requests cancelled",
+			gracePeriod: 0,
+			wantPages:   1,
+		},
+		{
+			name:        "in flight requests drained",
+			gracePeriod: time.Second,
+			wantPages:   3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := internal.NewCrawlOptions(target, true, 0, 2)
+			o.MaxDuration = time.Millisecond * 50
+			o.GracePeriod = tt.gracePeriod
+
+			got, err := internal.Crawl(slowLoader, internal.HtmlTokenExtractor, o)
+			if err != nil {
+				t.Fatalf("Crawl() error = %v", err)
+			}
+			if len(got.Pages()) != tt.wantPages {
+				t.Errorf("Crawl().Pages() got %d pages, want %d: %v", len(got.Pages()), tt.wantPages, got.URLs())
+			}
+			if got.TruncateReason() != internal.TruncatedMaxDuration {
+				t.Errorf("Crawl().TruncateReason() = %s, want %s", got.TruncateReason(), internal.TruncatedMaxDuration)
+			}
+			if len(got.FailedURLs()) != 0 {
+				t.Errorf("Crawl().FailedURLs() = %v, want none", got.FailedURLs())
+			}
+		})
+	}
+}
+
+func TestCrawl_Stop(t *testing.T) {
+	target := &url.URL{Scheme: "https", Host: "localhost", Path: "index.html"}
+	tests := []struct {
+		name        string
+		gracePeriod time.Duration
+		wantPages   int
+	}{
+		{
+			name:        "in flight requests cancelled",
+			gracePeriod: 0,
+			wantPages:   1,
+		},
+		{
+			name:        "in flight requests drained",
+			gracePeriod: time.Second,
+			wantPages:   3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Stop the crawl once both of the pages linked from the index have started loading
+			stop := make(chan struct{})
+			started := 0
+			mu := &sync.Mutex{}
+			var loader internal.LoaderFunc = func(ctx context.Context, p string) (*internal.Response, error) {
+				if p != target.String() {
+					mu.Lock()
+					started++
+					if started == 2 {
+						close(stop)
+					}
+					mu.Unlock()
+					select {
+					case <-time.After(time.Millisecond * 50):
+					case <-ctx.Done():
+						return nil, ctx.Err()
+					}
+				}
+				return testFileLoader(ctx, p)
+			}
+			o := internal.NewCrawlOptions(target, true, 0, 2)
+			o.Stop = stop
+			o.GracePeriod = tt.gracePeriod
+
+			got, err := internal.Crawl(loader, internal.HtmlTokenExtractor, o)
+			if err != nil {
+				t.Fatalf("Crawl() error = %v", err)
+			}
+			if len(got.Pages()) != tt.wantPages {
+				t.Errorf("Crawl().Pages() got %d pages, want %d: %v", len(got.Pages()), tt.wantPages, got.URLs())
+			}
+			if got.TruncateReason() != internal.TruncatedStopped {
+				t.Errorf("Crawl().TruncateReason() = %s, want %s", got.TruncateReason(), internal.TruncatedStopped)
+			}
+		})
+	}
+}
@@ -0,0 +1,275 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// DefaultCheckpointInterval is how often a crawl is checkpointed to its StateDir unless CheckpointInterval is set
+const DefaultCheckpointInterval = 30 * time.Second
+
+// ErrStateDir is matched by the error returned when the state directory can't be created, before anything is crawled
+var ErrStateDir = errors.New("failed to create state dir")
+
+// checkpointFile is the name of the file in the state directory that checkpoints are written to
+const checkpointFile = "checkpoint.json"
+
+// Checkpoint is the state of a crawl that was saved to a state directory, so that the crawl can be resumed
+type Checkpoint struct {
+	// seeds are the URLs that the crawl started from
+	seeds []*url.URL
+	// pages are the pages that had been crawled, by URL
+	pages map[string]Page
+	// excluded are the URLs that had been excluded by a pattern or robots.txt, and the reason why
+	excluded map[string]string
+	// pending are the requests that were queued or in flight
+	pending []crawlRequest
+	// dropped are the URLs that were dropped because the queue was full
+	dropped []string
+	// untracked is the number of URLs that were dropped once there were too many to keep track of
+	untracked int
+}
+
+// Seeds returns the URLs that the checkpointed crawl started from
+func (c *Checkpoint) Seeds() []*url.URL {
+	return c.seeds
+}
+
+// Pages returns the number of pages that had been crawled
+func (c *Checkpoint) Pages() int {
+	return len(c.pages)
+}
+
+// Pending returns the number of URLs that were waiting to be crawled, including any that were in flight
+func (c *Checkpoint) Pending() int {
+	return len(c.pending)
+}
+
+// restore adds the checkpointed pages to res and reqLog, and returns the requests that still need to be crawled.
+// The final URL of each page is modified by modifiers before it is marked as seen, as it is when the page is scraped.
+func (c *Checkpoint) restore(res Result, reqLog *requestLog, modifiers []URLModifyFunc) []crawlRequest {
+	for u, p := range c.pages {
+		res.pages[u] = p
+		if parsed, err := url.Parse(p.URL); err == nil {
+			reqLog.restore(parsed)
+		}
+		if final, err := url.Parse(p.FinalURL); err == nil {
+			reqLog.MarkAsSeen(ModifyURL(final, modifiers...))
+		}
+		reqLog.budget.addBytes(p.Size)
+	}
+	for u, reason := range c.excluded {
+		res.excluded[u] = reason
+	}
+	// Pending requests are crawled again even if their page was stored, as the links on it may not have been queued
+	for _, r := range c.pending {
+		reqLog.restore(r.target)
+	}
+	reqLog.mu.Lock()
+	for _, u := range c.dropped {
+		if !reqLog.seenURLs[u] {
+			reqLog.drop(u)
+		}
+	}
+	reqLog.untracked += c.untracked
+	reqLog.mu.Unlock()
+	return c.pending
+}
+
+// checkpointJSON is the format that a Checkpoint is saved in
+type checkpointJSON struct {
+	Seeds     []string            `json:"seeds"`
+	Pages     []checkpointPage    `json:"pages"`
+	Excluded  map[string]string   `json:"excluded"`
+	Pending   []checkpointRequest `json:"pending"`
+	Dropped   []string            `json:"dropped"`
+	Untracked int                 `json:"untracked,omitempty"`
+}
+
+// checkpointPage is a Page with an error that can be saved
+type checkpointPage struct {
+	Page
+	Err *checkpointError `json:",omitempty"`
+}
+
+// checkpointError is a PageError with the underlying error replaced by its message
+type checkpointError struct {
+	Kind     ErrorKind
+	Attempts int
+	Message  string
+}
+
+// checkpointRequest is a crawlRequest that can be saved
+type checkpointRequest struct {
+	URL    string
+	Origin string `json:",omitempty"`
+	Seed   string
+	Depth  int
+	Kind   LinkKind
+}
+
+// MarshalJSON encodes the Checkpoint in the format that it is saved in
+func (c *Checkpoint) MarshalJSON() ([]byte, error) {
+	out := checkpointJSON{
+		Seeds:     make([]string, len(c.seeds)),
+		Pages:     make([]checkpointPage, 0, len(c.pages)),
+		Excluded:  c.excluded,
+		Pending:   make([]checkpointRequest, len(c.pending)),
+		Dropped:   c.dropped,
+		Untracked: c.untracked,
+	}
+	for i, seed := range c.seeds {
+		out.Seeds[i] = seed.String()
+	}
+	for _, p := range c.pages {
+		cp := checkpointPage{Page: p}
+		if p.Err != nil {
+			cp.Err = &checkpointError{Kind: p.Err.Kind, Attempts: p.Err.Attempts, Message: p.Err.Err.Error()}
+		}
+		out.Pages = append(out.Pages, cp)
+	}
+	for i, r := range c.pending {
+		out.Pending[i] = checkpointRequest{URL: r.target.String(), Seed: r.seed.String(), Depth: r.depth, Kind: r.kind}
+		if r.origin != nil {
+			out.Pending[i].Origin = r.origin.String()
+		}
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON decodes a Checkpoint from the format that it is saved in
+func (c *Checkpoint) UnmarshalJSON(b []byte) error {
+	var in checkpointJSON
+	if err := json.Unmarshal(b, &in); err != nil {
+		return err
+	}
+	c.seeds = make([]*url.URL, len(in.Seeds))
+	for i, seed := range in.Seeds {
+		u, err := url.Parse(seed)
+		if err != nil {
+			return err
+		}
+		c.seeds[i] = u
+	}
+	c.pages = make(map[string]Page, len(in.Pages))
+	for _, cp := range in.Pages {
+		p := cp.Page
+		if cp.Err != nil {
+			p.Err = &PageError{URL: p.URL, Kind: cp.Err.Kind, Attempts: cp.Err.Attempts, Err: errors.New(cp.Err.Message)}
+		}
+		c.pages[p.URL] = p
+	}
+	c.excluded = in.Excluded
+	if c.excluded == nil {
+		c.excluded = make(map[string]string)
+	}
+	c.pending = make([]crawlRequest, len(in.Pending))
+	for i, r := range in.Pending {
+		target, err := url.Parse(r.URL)
+		if err != nil {
+			return err
+		}
+		req := crawlRequest{target: target, depth: r.Depth, kind: r.Kind}
+		if r.Origin != "" {
+			if req.origin, err = url.Parse(r.Origin); err != nil {
+				return err
+			}
+		}
+		if req.seed, err = url.Parse(r.Seed); err != nil {
+			return err
+		}
+		c.pending[i] = req
+	}
+	c.dropped = in.Dropped
+	c.untracked = in.Untracked
+	return nil
+}
+
+// LoadCheckpoint loads the Checkpoint that was last saved to the state directory
+func LoadCheckpoint(dir string) (*Checkpoint, error) {
+	b, err := ioutil.ReadFile(filepath.Join(dir, checkpointFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load checkpoint: %w", err)
+	}
+	c := &Checkpoint{}
+	if err := json.Unmarshal(b, c); err != nil {
+		return nil, fmt.Errorf("failed to load checkpoint: %w", err)
+	}
+	return c, nil
+}
+
+// saveCheckpoint writes the Checkpoint to the state directory. The previous checkpoint is replaced in a single
+// rename, so that it is never left half written.
+func saveCheckpoint(dir string, c *Checkpoint) error {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("failed to save checkpoint: %w", err)
+	}
+	f, err := ioutil.TempFile(dir, "checkpoint-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to save checkpoint: %w", err)
+	}
+	_, err = f.Write(b)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), filepath.Join(dir, checkpointFile))
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return fmt.Errorf("failed to save checkpoint: %w", err)
+	}
+	return nil
+}
+
+// checkpointer saves the state of a running crawl to a state directory
+type checkpointer struct {
+	dir    string
+	seeds  []*url.URL
+	queue  *frontier
+	res    Result
+	reqLog *requestLog
+	// mu ensures that only one checkpoint is saved at a time
+	mu *sync.Mutex
+}
+
+// save a Checkpoint of the crawl so far
+func (c *checkpointer) save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// The frontier is read first, so that a page that finishes in between is always in either pending or pages
+	pending := c.queue.pending()
+	pages, excluded := c.res.snapshot()
+	dropped, untracked := c.reqLog.droppedList()
+	return saveCheckpoint(c.dir, &Checkpoint{
+		seeds:     c.seeds,
+		pages:     pages,
+		excluded:  excluded,
+		pending:   pending,
+		dropped:   dropped,
+		untracked: untracked,
+	})
+}
+
+// run saves a Checkpoint every interval until ctx is done. Errors are ignored, as the next checkpoint may succeed.
+func (c *checkpointer) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			_ = c.save()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
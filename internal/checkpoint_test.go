@@ -0,0 +1,193 @@
+package internal_test
+
+import (
+	"context"
+	"errors"
+	"github.com/jmwri/web-crawler/internal"
+	"io/ioutil"
+	"net/url"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+)
+
+func TestCrawl_Resume(t *testing.T) {
+	dir := t.TempDir()
+	target := &url.URL{Scheme: "https", Host: "localhost", Path: "index.html"}
+
+	// Serve the index page, then cancel the crawl while the other pages are in flight
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var interrupted internal.LoaderFunc = func(ctx context.Context, p string) (*internal.Response, error) {
+		if p == "https://localhost/index.html" {
+			return testFileLoader(ctx, p)
+		}
+		cancel()
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	o := internal.NewCrawlOptions(target, true, 0, 0)
+	o.StateDir = dir
+	_, err := internal.CrawlContext(ctx, interrupted, internal.HtmlTokenExtractor, o)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("CrawlContext() error = %v, want %v", err, context.Canceled)
+	}
+
+	cp, err := internal.LoadCheckpoint(dir)
+	if err != nil {
+		t.Fatalf("LoadCheckpoint() error = %v", err)
+	}
+	if cp.Pages() != 1 {
+		t.Errorf("Checkpoint.Pages() = %d, want 1", cp.Pages())
+	}
+	if cp.Pending() != 2 {
+		t.Errorf("Checkpoint.Pending() = %d, want 2", cp.Pending())
+	}
+	if len(cp.Seeds()) != 1 || cp.Seeds()[0].String() != target.String() {
+		t.Errorf("Checkpoint.Seeds() = %v, want [%s]", cp.Seeds(), target)
+	}
+
+	// Resume the crawl, which should only load the pages that weren't crawled
+	loaded := make([]string, 0)
+	mu := &sync.Mutex{}
+	var resumed internal.LoaderFunc = func(ctx context.Context, p string) (*internal.Response, error) {
+		mu.Lock()
+		loaded = append(loaded, p)
+		mu.Unlock()
+		return testFileLoader(ctx, p)
+	}
+	o.Resume = cp
+	got, err := internal.Crawl(resumed, internal.HtmlTokenExtractor, o)
+	if err != nil {
+		t.Fatalf("Crawl() error = %v", err)
+	}
+	sort.Strings(loaded)
+	wantLoaded := []string{"https://localhost/about.html", "https://localhost/contact.html"}
+	if !reflect.DeepEqual(loaded, wantLoaded) {
+		t.Errorf("Crawl() loaded %v, want %v", loaded, wantLoaded)
+	}
+	want, err := internal.Crawl(testFileLoader, internal.HtmlTokenExtractor, internal.NewCrawlOptions(target, true, 0, 0))
+	if err != nil {
+		t.Fatalf("Crawl() error = %v", err)
+	}
+	if !reflect.DeepEqual(got.URLs(), want.URLs()) {
+		t.Errorf("Crawl().URLs() got = %v, want %v", got.URLs(), want.URLs())
+	}
+	if got.Truncated() {
+		t.Errorf("Crawl().Truncated() = true, want false")
+	}
+
+	// The finished crawl should leave nothing pending
+	cp, err = internal.LoadCheckpoint(dir)
+	if err != nil {
+		t.Fatalf("LoadCheckpoint() error = %v", err)
+	}
+	if cp.Pages() != 3 || cp.Pending() != 0 {
+		t.Errorf("Checkpoint has %d pages and %d pending, want 3 and 0", cp.Pages(), cp.Pending())
+	}
+}
+
+func TestCrawl_StopResume(t *testing.T) {
+	dir := t.TempDir()
+	target := &url.URL{Scheme: "https", Host: "localhost", Path: "/index.html"}
+	pages := map[string]string{
+		"/index.html": `<a href="/a.html">a</a><a href="/b.html">b</a>`,
+		"/a.html":     `<a href="/c.html">c</a>`,
+		"/b.html":     ``,
+		"/c.html":     ``,
+	}
+
+	// Stop the crawl once a.html has loaded, and finish a.html after the grace period has cancelled b.html, so that
+	// the link to c.html is found after the crawl stopped
+	stop := make(chan struct{})
+	aLoaded := make(chan struct{})
+	bCancelled := make(chan struct{})
+	var stopped internal.LoaderFunc = func(ctx context.Context, p string) (*internal.Response, error) {
+		switch p {
+		case "https://localhost/a.html":
+			close(aLoaded)
+		case "https://localhost/b.html":
+			<-aLoaded
+			close(stop)
+			<-ctx.Done()
+			close(bCancelled)
+			return nil, ctx.Err()
+		}
+		return testStringLoader(pages)(ctx, p)
+	}
+	o := internal.NewCrawlOptions(target, true, 0, 2)
+	o.StateDir = dir
+	o.Stop = stop
+	o.OnPage = func(e internal.PageEvent) {
+		if e.URL.Path == "/a.html" {
+			<-bCancelled
+		}
+	}
+	got, err := internal.Crawl(stopped, internal.HtmlTokenExtractor, o)
+	if err != nil {
+		t.Fatalf("Crawl() error = %v", err)
+	}
+	if got.TruncateReason() != internal.TruncatedStopped {
+		t.Errorf("Crawl().TruncateReason() = %s, want %s", got.TruncateReason(), internal.TruncatedStopped)
+	}
+
+	cp, err := internal.LoadCheckpoint(dir)
+	if err != nil {
+		t.Fatalf("LoadCheckpoint() error = %v", err)
+	}
+	if cp.Pages() != 2 || cp.Pending() != 2 {
+		t.Errorf("Checkpoint has %d pages and %d pending, want 2 and 2", cp.Pages(), cp.Pending())
+	}
+
+	// Resume the crawl, which should load the page that was cancelled and the page that was found after the stop
+	loaded := make([]string, 0)
+	mu := &sync.Mutex{}
+	var resumed internal.LoaderFunc = func(ctx context.Context, p string) (*internal.Response, error) {
+		mu.Lock()
+		loaded = append(loaded, p)
+		mu.Unlock()
+		return testStringLoader(pages)(ctx, p)
+	}
+	o = internal.NewCrawlOptions(target, true, 0, 2)
+	o.StateDir = dir
+	o.Resume = cp
+	got, err = internal.Crawl(resumed, internal.HtmlTokenExtractor, o)
+	if err != nil {
+		t.Fatalf("Crawl() error = %v", err)
+	}
+	sort.Strings(loaded)
+	wantLoaded := []string{"https://localhost/b.html", "https://localhost/c.html"}
+	if !reflect.DeepEqual(loaded, wantLoaded) {
+		t.Errorf("Crawl() loaded %v, want %v", loaded, wantLoaded)
+	}
+	if len(got.Pages()) != 4 || got.Truncated() {
+		t.Errorf("Crawl() got %d pages and Truncated() = %v, want 4 and false: %v", len(got.Pages()), got.Truncated(), got.URLs())
+	}
+}
+
+func TestLoadCheckpoint_Missing(t *testing.T) {
+	_, err := internal.LoadCheckpoint(t.TempDir())
+	if err == nil {
+		t.Errorf("LoadCheckpoint() error = nil, want an error")
+	}
+}
+
+func TestCrawl_StateDirUnusable(t *testing.T) {
+	// A state dir inside a file can't be created
+	file := filepath.Join(t.TempDir(), "file")
+	if err := ioutil.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	target := &url.URL{Scheme: "https", Host: "localhost", Path: "index.html"}
+	o := internal.NewCrawlOptions(target, true, 0, 0)
+	o.StateDir = filepath.Join(file, "state")
+	got, err := internal.Crawl(testFileLoader, internal.HtmlTokenExtractor, o)
+	if !errors.Is(err, internal.ErrStateDir) {
+		t.Fatalf("Crawl() error = %v, want %v", err, internal.ErrStateDir)
+	}
+	if len(got.Pages()) != 0 {
+		t.Errorf("Crawl().Pages() got %d pages, want 0", len(got.Pages()))
+	}
+}
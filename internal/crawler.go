@@ -1,11 +1,17 @@
 package internal
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
 	"net/url"
+	"os"
 	"sync"
+	"time"
 )
 
-// NewCrawlOptions returns new CrawlOptions with sanitised input
+// NewCrawlOptions returns new CrawlOptions with sanitised input. sameDomain restricts crawling to ScopeHost.
 func NewCrawlOptions(target *url.URL, sameDomain bool, maxDepth int, workers int) CrawlOptions {
 	if maxDepth < 0 {
 		maxDepth = 0
@@ -13,11 +19,15 @@ func NewCrawlOptions(target *url.URL, sameDomain bool, maxDepth int, workers int
 	if workers < 1 {
 		workers = 10
 	}
+	scope := ScopeAll
+	if sameDomain {
+		scope = ScopeHost
+	}
 	return CrawlOptions{
-		Target:     target,
-		SameDomain: sameDomain,
-		MaxDepth:   maxDepth,
-		Workers:    workers,
+		Target:   target,
+		Scope:    scope,
+		MaxDepth: maxDepth,
+		Workers:  workers,
 	}
 }
 
@@ -25,12 +35,86 @@ func NewCrawlOptions(target *url.URL, sameDomain bool, maxDepth int, workers int
 type CrawlOptions struct {
 	// Target is the URL to start crawling from
 	Target *url.URL
-	// SameDomain restricts crawling to the same domain
-	SameDomain bool
+	// Seeds are additional URLs to start crawling from, alongside Target. Every seed shares the same workers and never
+	// crawls a URL that another seed has already crawled.
+	Seeds []*url.URL
+	// Scope restricts crawling to URLs that are within the scope of the seed that the page they were found on was
+	// reached from
+	Scope Scope
+	// IgnoreScheme treats http and https URLs as the same site when applying Scope
+	IgnoreScheme bool
 	// MaxDepth is the max depth that the crawler will go to. Set to 0 for no max depth.
 	MaxDepth int
 	// Workers is the amount of Workers that will be used to crawl. Must be at least 1.
 	Workers int
+	// MaxPages is the max number of pages that will be crawled. Set to 0 for no max pages.
+	MaxPages int
+	// MaxPagesPerHost is the max number of pages that will be crawled on each host. Set to 0 for no max.
+	MaxPagesPerHost int
+	// MaxBytes stops the crawl once this many bytes have been read from pages. Set to 0 for no max bytes.
+	MaxBytes int64
+	// MaxDuration stops the crawl once it has been running this long. Set to 0 for no max duration.
+	MaxDuration time.Duration
+	// GracePeriod is how long requests that are in flight when MaxDuration is reached, or Stop is closed, have to
+	// finish before they are cancelled. Cancelled requests are left out of the Result. Set to 0 to cancel them
+	// immediately.
+	GracePeriod time.Duration
+	// Stop stops the crawl when it is closed. No new requests are made, requests that are in flight have GracePeriod
+	// to finish, and the Result is truncated with TruncatedStopped. Leave nil to only stop when ctx is done.
+	Stop <-chan struct{}
+	// CheckResources requests links that aren't LinkKindNavigation, such as images and scripts, to check that they
+	// load. They are never scraped for links. Form actions are never requested, as submitting a form can have side
+	// effects. When false they are only recorded as edges.
+	CheckResources bool
+	// OnPage is called with a PageEvent for each page as soon as the crawler has finished with it.
+	// Calls are never made concurrently, so a slow OnPage will slow down the crawl.
+	OnPage func(PageEvent)
+	// Robots filters out URLs that are disallowed by robots.txt. Set to nil to ignore robots.txt.
+	Robots *RobotsCache
+	// UserAgent is matched against X-Robots-Tag headers that are scoped to a user agent, ie "otherbot: nofollow".
+	// Scoped headers are ignored if it is empty.
+	UserAgent string
+	// Include restricts crawling to discovered URLs that match at least one pattern. Empty includes everything.
+	Include []URLPattern
+	// Exclude skips discovered URLs that match any pattern
+	Exclude []URLPattern
+	// Filters are applied to discovered URLs after the standard filters
+	Filters []URLFilterFunc
+	// Modifiers are applied to discovered URLs before the standard modifiers, so that the standard modifiers always
+	// have the last say, ie a trailing slash left by RemoveDotSegments is still removed
+	Modifiers []URLModifyFunc
+	// Priority orders the URLs waiting to be crawled. Defaults to BreadthFirstPriority.
+	Priority PriorityFunc
+	// MaxQueue is the max number of URLs that can wait to be crawled at once. URLs that are discovered while the
+	// queue is full are dropped, but can be queued again if they are found on another page. Defaults to
+	// DefaultMaxQueue.
+	MaxQueue int
+	// StrictDepth crawls one depth at a time, only starting on a depth once every page at the depth before has been
+	// crawled. Every page is then found at its shortest depth from a seed, so MaxDepth gives the same pages on every
+	// run, but workers can sit idle while the last pages at each depth are crawled.
+	StrictDepth bool
+	// StateDir is the directory that the crawl is checkpointed to, so that it can be resumed with LoadCheckpoint.
+	// It is created if it doesn't exist. Leave empty to disable checkpoints.
+	StateDir string
+	// CheckpointInterval is how often the crawl is checkpointed to StateDir. A checkpoint is always saved when the
+	// crawl finishes. Defaults to DefaultCheckpointInterval.
+	CheckpointInterval time.Duration
+	// Resume continues the crawl from a Checkpoint. Pages in the Checkpoint aren't crawled again, and URLs that were
+	// waiting or in flight when it was saved are crawled first.
+	Resume *Checkpoint
+}
+
+// maxQueue returns MaxQueue, or DefaultMaxQueue if it isn't set
+func (o CrawlOptions) maxQueue() int {
+	if o.MaxQueue > 0 {
+		return o.MaxQueue
+	}
+	return DefaultMaxQueue
+}
+
+// seeds returns Target followed by any additional Seeds
+func (o CrawlOptions) seeds() []*url.URL {
+	return append([]*url.URL{o.Target}, o.Seeds...)
 }
 
 // crawlRequest defines a single request that the crawler should perform
@@ -41,14 +125,20 @@ type crawlRequest struct {
 	target *url.URL
 	// depth the depth that the page was discovered
 	depth int
+	// kind is the kind of link that the target was found in. Only LinkKindNavigation targets are scraped.
+	kind LinkKind
+	// seed is the seed that the target was reached from
+	seed *url.URL
 }
 
-// next returns a new crawlRequest to the given target
-func (r crawlRequest) next(target *url.URL) crawlRequest {
+// next returns a new crawlRequest to the target of the given link
+func (r crawlRequest) next(l Link) crawlRequest {
 	return crawlRequest{
 		origin: r.target,
-		target: target,
+		target: l.URL,
 		depth:  r.depth + 1,
+		kind:   l.Kind,
+		seed:   r.seed,
 	}
 }
 
@@ -57,220 +147,681 @@ type crawlResponse struct {
 	// request is the request the triggered the page being scraped
 	request crawlRequest
 	// err is present if the crawler failed to scrape the page
-	err error
-	// urls are the links found on the page
-	urls []*url.URL
-}
-
-// Result contains all of the URLs discovered and visited
-type Result struct {
-	// options are the options that the Crawler was executed against
-	options CrawlOptions
-	// crawledURLs is a map of URL to URLs discovered on that page
-	crawledURLs map[string][]string
-	// mu is an internal mutex to ensure routine safe access of crawledURLs
-	mu *sync.Mutex
-}
-
-// Store discovered URLs against a URL
-func (r Result) Store(u *url.URL, urls []string) {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-	r.crawledURLs[u.String()] = urls
+	err *PageError
+	// links are the links found on the page
+	links []Link
+	// noFollow is true if the page asked for none of its links to be followed
+	noFollow bool
+	// noIndex is true if the page asked not to be indexed
+	noIndex bool
+	// statusCode is the HTTP status code of the page, if loaded over HTTP
+	statusCode int
+	// contentType is the Content-Type header of the page, if any
+	contentType string
+	// size is the number of bytes read from the page
+	size int64
+	// latency is the time taken to load and read the page
+	latency time.Duration
+	// finalURL is the URL of the page after any redirects
+	finalURL *url.URL
+	// redirects are the hops that were followed to reach finalURL
+	redirects []Redirect
+	// duplicate is true if finalURL had already been seen, so the page wasn't scraped
+	duplicate bool
 }
 
-// Target is the URL that the crawler started at
-func (r Result) Target() *url.URL {
-	return r.options.Target
-}
-
-// SameDomain specifies if the crawler limited to the same domain
-func (r Result) SameDomain() bool {
-	return r.options.SameDomain
+// page builds the Page that is stored in the Result
+func (r crawlResponse) page() Page {
+	p := Page{
+		URL:         r.request.target.String(),
+		Depth:       r.request.depth,
+		Kind:        r.request.kind,
+		StatusCode:  r.statusCode,
+		ContentType: r.contentType,
+		Size:        r.size,
+		Latency:     r.latency,
+		FinalURL:    r.finalURL.String(),
+		Redirects:   r.redirects,
+		Duplicate:   r.duplicate,
+		NoFollow:    r.noFollow,
+		NoIndex:     r.noIndex,
+		Edges:       linksToEdges(r.links),
+		Err:         r.err,
+	}
+	if r.request.origin != nil {
+		p.Origin = r.request.origin.String()
+	}
+	if r.request.seed != nil {
+		p.Seed = r.request.seed.String()
+	}
+	return p
 }
 
-// MaxDepth returns the max depth of the crawler
-func (r Result) MaxDepth() int {
-	return r.options.MaxDepth
+// PageEvent describes a single page that the crawler has finished with
+type PageEvent struct {
+	// URL is the URL of the page
+	URL *url.URL
+	// Depth is the depth that the page was discovered at. Seeds are at depth 1.
+	Depth int
+	// Origin is the URL of the page that the link was found on. It is nil for seeds.
+	Origin *url.URL
+	// Seed is the seed that the page was reached from
+	Seed *url.URL
+	// Err is present if the crawler failed to scrape the page
+	Err *PageError
+	// Links are the URLs found on the page, after filtering
+	Links []*url.URL
+	// Queued is the number of URLs that were waiting to be crawled when the page was finished
+	Queued int
 }
 
-// URLs returns a map of URLs that the crawler visited, and a list of URLs found on that page
-func (r Result) URLs() map[string][]string {
-	return r.crawledURLs
+// pageEventHandler returns a function that passes a PageEvent for each crawlResponse to onPage, one at a time.
+// queued returns the number of URLs waiting to be crawled. It returns a no-op if onPage is nil.
+func pageEventHandler(onPage func(PageEvent), queued func() int) func(crawlResponse) {
+	if onPage == nil {
+		return func(crawlResponse) {}
+	}
+	mu := &sync.Mutex{}
+	return func(r crawlResponse) {
+		mu.Lock()
+		defer mu.Unlock()
+		onPage(PageEvent{
+			URL:    r.request.target,
+			Depth:  r.request.depth,
+			Origin: r.request.origin,
+			Seed:   r.request.seed,
+			Err:    r.err,
+			Links:  linkURLs(r.links),
+			Queued: queued(),
+		})
+	}
 }
 
 // requestLog stores URLs that we have previously seen and issued requests for
 type requestLog struct {
 	// seenURLs contains URLs that we have already requested, or are trying to request
 	seenURLs map[string]bool
-	// mu is an internal mutex to ensure routine safe access of seenURLs
+	// budget limits the URLs that can be claimed
+	budget *budget
+	// droppedURLs contains URLs that were released without being requested, and haven't been claimed since
+	droppedURLs map[string]bool
+	// maxDropped is the max number of URLs that are kept in droppedURLs, so that it uses no more memory than the queue
+	maxDropped int
+	// untracked is the number of URLs that were released while droppedURLs was full. They always count as dropped,
+	// even if they are claimed again later.
+	untracked int
+	// mu is an internal mutex to ensure routine safe access of seenURLs, droppedURLs and untracked
 	mu *sync.Mutex
 }
 
-// Seen returns whether or not the given URL has been seen before
-func (l requestLog) Seen(u *url.URL) bool {
+// Claim marks the given URL as seen so that it can be requested, and returns true if it wasn't seen before.
+// Returns false without marking the URL if the budget has run out.
+func (l *requestLog) Claim(u *url.URL) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.seenURLs[u.String()] {
+		return false
+	}
+	if !l.budget.claim(u) {
+		return false
+	}
+	l.seenURLs[u.String()] = true
+	delete(l.droppedURLs, u.String())
+	return true
+}
+
+// Keep marks the given URL as seen without requesting it, so that it is checkpointed as pending after the crawl has
+// stopped. Returns true if it wasn't seen before, and the budget hasn't run out.
+func (l *requestLog) Keep(u *url.URL) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.seenURLs[u.String()] {
+		return false
+	}
+	if !l.budget.keep(u) {
+		return false
+	}
+	l.seenURLs[u.String()] = true
+	delete(l.droppedURLs, u.String())
+	return true
+}
+
+// Release gives up the claim on a URL that won't be requested, so that it can be claimed again if it is found later
+func (l *requestLog) Release(u *url.URL) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.seenURLs, u.String())
+	l.budget.release(u)
+	l.drop(u.String())
+}
+
+// drop records a URL as dropped, or counts it as untracked if droppedURLs is full. mu must be held.
+func (l *requestLog) drop(u string) {
+	if l.droppedURLs[u] {
+		return
+	}
+	if len(l.droppedURLs) >= l.maxDropped {
+		l.untracked++
+		return
+	}
+	l.droppedURLs[u] = true
+}
+
+// Dropped returns the number of URLs that were released and never claimed again, along with any that weren't
+// tracked
+func (l *requestLog) Dropped() int {
 	l.mu.Lock()
 	defer l.mu.Unlock()
-	return l.seenURLs[u.String()]
+	return len(l.droppedURLs) + l.untracked
+}
+
+// droppedList returns the URLs that were released and never claimed again, and the number that weren't tracked
+func (l *requestLog) droppedList() ([]string, int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	dropped := make([]string, 0, len(l.droppedURLs))
+	for u := range l.droppedURLs {
+		dropped = append(dropped, u)
+	}
+	return dropped, l.untracked
 }
 
-// MarkAsSeen marks the given URL as seen
-func (l requestLog) MarkAsSeen(u *url.URL) {
+// restore marks a URL that was claimed before the crawl was resumed as seen, using up its budget
+func (l *requestLog) restore(u *url.URL) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
+	if l.seenURLs[u.String()] {
+		return
+	}
+	l.seenURLs[u.String()] = true
+	l.budget.use(u)
+}
+
+// MarkAsSeen marks the given URL as seen without claiming it, and returns true if it wasn't seen before.
+// It is used for URLs that have already been loaded by following a redirect.
+func (l *requestLog) MarkAsSeen(u *url.URL) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.seenURLs[u.String()] {
+		return false
+	}
 	l.seenURLs[u.String()] = true
+	return true
+}
+
+// seedFilters builds the filters for each seed when they are first needed, as Scope is relative to the seed that a
+// page was reached from
+type seedFilters struct {
+	// build returns the filters for a seed
+	build func(seed *url.URL) []URLFilterFunc
+	// robots checks requests against robots.txt, as seeds aren't filtered when they are queued, and links found after
+	// the crawl stopped may not have been checked. It is nil if robots.txt is ignored.
+	robots *RobotsCache
+	// exclude records URLs that were excluded, and the reason why
+	exclude func(u *url.URL, reason string)
+	// filters are the filters that have been built, by seed
+	filters map[string][]URLFilterFunc
+	// mu is an internal mutex to ensure routine safe access of filters
+	mu *sync.Mutex
 }
 
-// buildFilters according the the provided options
-func buildFilters(o CrawlOptions) []URLFilterFunc {
+// get returns the filters for links on pages that were reached from the seed
+func (f *seedFilters) get(seed *url.URL) []URLFilterFunc {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	filters, ok := f.filters[seed.String()]
+	if !ok {
+		filters = f.build(seed)
+		f.filters[seed.String()] = filters
+	}
+	return filters
+}
+
+// robotsExcludeReason is the reason that seeds disallowed by robots.txt are excluded
+const robotsExcludeReason = "disallowed by robots.txt"
+
+// allowed returns whether robots.txt allows the request to be crawled. Disallowed seeds are excluded. robots.txt is
+// loaded with ctx.
+func (f *seedFilters) allowed(ctx context.Context, r crawlRequest) bool {
+	if f.robots == nil || f.robots.Rules(ctx, r.target).Allowed(r.target) {
+		return true
+	}
+	if r.origin == nil {
+		f.exclude(r.target, robotsExcludeReason)
+	}
+	return false
+}
+
+// buildFilters according the the provided options. ctx is used by filters that make requests, which stop loading
+// robots.txt for new hosts once stopped returns true. URLs excluded by the Include and Exclude patterns are recorded in
+// res.
+func buildFilters(ctx context.Context, stopped func() bool, o CrawlOptions, res Result) *seedFilters {
+	return &seedFilters{
+		build: func(seed *url.URL) []URLFilterFunc {
+			return buildSeedFilters(ctx, stopped, o, res, seed)
+		},
+		robots:  o.Robots,
+		exclude: res.Exclude,
+		filters: make(map[string][]URLFilterFunc),
+		mu:      &sync.Mutex{},
+	}
+}
+
+// buildSeedFilters returns the filters for links on pages that were reached from the seed
+func buildSeedFilters(ctx context.Context, stopped func() bool, o CrawlOptions, res Result, seed *url.URL) []URLFilterFunc {
 	filters := []URLFilterFunc{
 		RemoveNonHTTPURLs,
 		DedupeURLs,
 	}
-	if o.SameDomain {
-		filters = append(filters, SameDomainFilter(o.Target))
+	if o.Scope != ScopeAll {
+		filters = append(filters, ScopeFilter(o.Scope, o.IgnoreScheme, seed))
 	}
-	return filters
+	if len(o.Include) > 0 || len(o.Exclude) > 0 {
+		filters = append(filters, PatternFilter(o.Include, o.Exclude, res.Exclude))
+	}
+	if o.Robots != nil {
+		filters = append(filters, robotsFilter(ctx, o.Robots, stopped))
+	}
+	return append(filters, o.Filters...)
 }
 
-// buildModifiers returns the standard modifiers
-func buildModifiers() []URLModifyFunc {
-	return []URLModifyFunc{
-		RemoveTrailingSlash,
-		RemoveFragment,
-	}
+// buildModifiers returns any modifiers in the provided options, followed by the standard modifiers
+func buildModifiers(o CrawlOptions) []URLModifyFunc {
+	modifiers := make([]URLModifyFunc, 0, len(o.Modifiers)+2)
+	modifiers = append(modifiers, o.Modifiers...)
+	return append(modifiers, RemoveTrailingSlash, RemoveFragment)
 }
 
 // Crawl according to the specified options
 func Crawl(loader LoaderFunc, extractor ExtractorFunc, o CrawlOptions) (Result, error) {
-	filters := buildFilters(o)
-	modifiers := buildModifiers()
+	return CrawlContext(context.Background(), loader, extractor, o)
+}
+
+// CrawlContext crawls according to the specified options until there is nothing left to crawl, or ctx is done.
+// If ctx is done first, the partial Result is returned along with the context error.
+func CrawlContext(ctx context.Context, loader LoaderFunc, extractor ExtractorFunc, o CrawlOptions) (Result, error) {
+	modifiers := buildModifiers(o)
 
 	// Initialise our result
 	res := Result{
-		options:     o,
-		crawledURLs: make(map[string][]string),
+		options:  o,
+		pages:    make(map[string]Page),
+		excluded: make(map[string]string),
+		mu:       &sync.Mutex{},
+	}
+	b := newBudget(o)
+	reqLog := &requestLog{
+		seenURLs:    make(map[string]bool),
+		budget:      b,
+		droppedURLs: make(map[string]bool),
+		maxDropped:  o.maxQueue(),
 		mu:          &sync.Mutex{},
 	}
-	reqLog := requestLog{
-		seenURLs: make(map[string]bool),
-		mu:       &sync.Mutex{},
+
+	if o.StateDir != "" {
+		if err := os.MkdirAll(o.StateDir, 0755); err != nil {
+			return res, fmt.Errorf("%w: %v", ErrStateDir, err)
+		}
 	}
 
-	// wg tracks the number of URLs currently being processed
+	// workerCtx is cancelled once the crawl is complete, so that idle Workers exit
+	workerCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// loadCtx is used to load pages and robots.txt, so that in flight requests can be cancelled without stopping the
+	// Workers
+	loadCtx, cancelLoads := context.WithCancel(workerCtx)
+	defer cancelLoads()
+	filters := buildFilters(loadCtx, b.isStopped, o, res)
+	if o.MaxDuration > 0 || o.Stop != nil {
+		go func() {
+			// A nil channel is never ready, so there is no timeout without MaxDuration
+			var timeout <-chan time.Time
+			if o.MaxDuration > 0 {
+				timer := time.NewTimer(o.MaxDuration)
+				defer timer.Stop()
+				timeout = timer.C
+			}
+			select {
+			case <-timeout:
+				b.stop(TruncatedMaxDuration)
+			case <-o.Stop:
+				b.stop(TruncatedStopped)
+			case <-workerCtx.Done():
+				return
+			}
+			if sleep(workerCtx, o.GracePeriod) != nil {
+				return
+			}
+			cancelLoads()
+		}()
+	}
+
+	// wg tracks the number of URLs currently queued or being processed
 	wg := &sync.WaitGroup{}
+	queue := newFrontier(o, wg)
+	onPage := pageEventHandler(o.OnPage, queue.len)
+	// Pick up where the checkpoint left off, before any workers can change the result
+	if o.Resume != nil {
+		enqueue(reqLog, queue, o.Resume.restore(res, reqLog, modifiers))
+	}
 	reqCh := make(chan crawlRequest)
 	resCh := make(chan crawlResponse)
 
+	go queue.dispatch(workerCtx, reqCh)
 	for i := 0; i < o.Workers; i++ {
-		go requestWorker(loader, extractor, reqCh, resCh, filters, modifiers)
+		go requestWorker(workerCtx, loadCtx, queue, loader, extractor, o.UserAgent, reqLog, reqCh, resCh, filters, modifiers)
 	}
 	for i := 0; i < o.Workers; i++ {
-		go responseWorker(wg, o.MaxDepth, reqLog, res, reqCh, resCh)
+		go responseWorker(workerCtx, o.MaxDepth, o.CheckResources, reqLog, res, onPage, queue, resCh)
+	}
+
+	var cp *checkpointer
+	if o.StateDir != "" {
+		cp = &checkpointer{dir: o.StateDir, seeds: o.seeds(), queue: queue, res: res, reqLog: reqLog, mu: &sync.Mutex{}}
+		interval := o.CheckpointInterval
+		if interval <= 0 {
+			interval = DefaultCheckpointInterval
+		}
+		go cp.run(workerCtx, interval)
 	}
 
-	// Queue the initial request
-	wg.Add(1)
-	reqLog.MarkAsSeen(o.Target)
-	reqCh <- crawlRequest{
-		target: o.Target,
-		depth:  1,
+	// Queue the seeds that weren't already crawled before the checkpoint. They are checked against robots.txt by the
+	// workers, so that the robots.txt files of different seeds are loaded in parallel.
+	seeds := make([]crawlRequest, 0)
+	for _, seed := range o.seeds() {
+		if !reqLog.Claim(seed) {
+			continue
+		}
+		seeds = append(seeds, crawlRequest{
+			target: seed,
+			depth:  1,
+			seed:   seed,
+		})
 	}
-	// Wait for all URLs to be processed
+	enqueue(reqLog, queue, seeds)
+	// Wait for all URLs to be processed, or dropped if ctx is done
 	wg.Wait()
-	// Close channels so Workers exit
-	close(reqCh)
-	close(resCh)
 
-	return res, nil
+	if ctx.Err() != nil {
+		b.stop(TruncatedCancelled)
+	}
+	if reqLog.Dropped() > 0 {
+		b.stop(TruncatedMaxQueue)
+	}
+	res.truncateReason = b.truncateReason()
+	res.queueStats = queue.queueStats()
+	if o.Robots != nil {
+		res.sitemaps = o.Robots.Sitemaps()
+	}
+	if cp != nil {
+		if err := cp.save(); err != nil && ctx.Err() == nil {
+			return res, err
+		}
+	}
+	return res, ctx.Err()
+}
+
+// enqueue pushes the requests to the frontier, releasing the URLs of any that are dropped
+func enqueue(reqLog *requestLog, queue *frontier, reqs []crawlRequest) {
+	for _, r := range queue.push(reqs...) {
+		reqLog.Release(r.target)
+	}
 }
 
-// requestWorker creates a crawlResponse based on the crawlRequest and sends it to responseWorker
-func requestWorker(loader LoaderFunc, extractor ExtractorFunc, reqCh <-chan crawlRequest, resCh chan<- crawlResponse, filters []URLFilterFunc, modifiers []URLModifyFunc) {
-	for r := range reqCh {
+// requestWorker creates a crawlResponse based on the crawlRequest and sends it to responseWorker.
+// Pages are loaded with loadCtx, and requests are dropped once the budget has stopped the crawl.
+func requestWorker(ctx context.Context, loadCtx context.Context, queue *frontier, loader LoaderFunc, extractor ExtractorFunc, userAgent string, reqLog *requestLog, reqCh <-chan crawlRequest, resCh chan<- crawlResponse, filters *seedFilters, modifiers []URLModifyFunc) {
+	for {
+		var r crawlRequest
+		select {
+		case r = <-reqCh:
+		case <-ctx.Done():
+			return
+		}
+
+		// Drop requests that were queued before the budget ran out
+		if reqLog.budget.isStopped() {
+			queue.abandon(r)
+			continue
+		}
+
+		// Seeds weren't filtered when they were queued, and links found after the crawl stopped may not have been
+		// checked, so they are dropped here if robots.txt disallows them
+		if !filters.allowed(loadCtx, r) {
+			reqLog.budget.release(r.target)
+			queue.done(r)
+			continue
+		}
+
 		// Find the links on the page
-		urls, err := scrapeURLs(loader, extractor, r)
+		res := scrape(loadCtx, loader, extractor, userAgent, reqLog, modifiers, r)
+		reqLog.budget.addBytes(res.size)
+		if loadCtx.Err() != nil {
+			// The request was cancelled when the grace period ended or the crawl was cancelled, so the page wasn't
+			// crawled
+			queue.abandon(r)
+			continue
+		}
 
-		// Normalise and filter the URLs
-		urls = ModifyURLs(urls, modifiers...)
-		urls = FilterURLs(urls, filters...)
+		// Normalise and filter the links
+		res.links = filterLinks(res.links, filters.get(r.seed), modifiers)
 
 		// Send the URLs to the next worker
-		resCh <- crawlResponse{
-			request: r,
-			err:     err,
-			urls:    urls,
+		select {
+		case resCh <- res:
+		case <-ctx.Done():
+			queue.abandon(r)
+			return
 		}
 	}
 }
 
 // responseWorker stores and initiates requests for scraped URLs
-func responseWorker(wg *sync.WaitGroup, maxDepth int, reqLog requestLog, res Result, reqCh chan<- crawlRequest, resCh <-chan crawlResponse) {
-	for r := range resCh {
-		// Store the scraped URLs against the URL they were found on
-		res.Store(r.request.target, urlsToString(r.urls))
+func responseWorker(ctx context.Context, maxDepth int, checkResources bool, reqLog *requestLog, res Result, onPage func(crawlResponse), queue *frontier, resCh <-chan crawlResponse) {
+	for {
+		var r crawlResponse
+		select {
+		case r = <-resCh:
+		case <-ctx.Done():
+			return
+		}
+
+		// Store the page, including the URLs found on it
+		res.Store(r.page())
+		onPage(r)
 
 		// Build the next set of requests
-		next := nextRequests(r.request, r.urls)
+		next := nextRequests(r.request, followableLinks(r, checkResources))
 
+		claimed := make([]crawlRequest, 0)
 		for _, n := range next {
 			// Keep crawling until we reach max depth
 			if maxDepth > 0 && n.depth > maxDepth {
 				continue
 			}
-			// Skip links we've already seen somewhere else
-			if reqLog.Seen(n.target) {
+			// Skip links we've already seen somewhere else, or that would take us over max pages. Links found after the
+			// crawl stopped are kept, so that they are pending when the crawl is resumed.
+			if !reqLog.Claim(n.target) && !(reqLog.budget.isStopped() && reqLog.Keep(n.target)) {
 				continue
 			}
-			reqLog.MarkAsSeen(n.target)
-			// Send the request back to requestWorker
-			// Write to channel in a routine to avoid a deadlock
-			wg.Add(1)
-			go func(r crawlRequest) {
-				reqCh <- r
-			}(n)
+			claimed = append(claimed, n)
 		}
-		wg.Done()
+		// Queue the requests together, so that they are prioritised against each other
+		enqueue(reqLog, queue, claimed)
+		queue.done(r.request)
 	}
 }
 
-// urlsToString converts a slice of url.URL to a slice of string
-func urlsToString(urls []*url.URL) []string {
-	res := make([]string, len(urls))
-	for i, u := range urls {
-		res[i] = u.String()
+// linkURLs returns the URL of each link
+func linkURLs(links []Link) []*url.URL {
+	urls := make([]*url.URL, len(links))
+	for i, l := range links {
+		urls[i] = l.URL
 	}
-	return res
+	return urls
 }
 
-// scrapeURLs from the requests Target
-func scrapeURLs(loader LoaderFunc, extractor ExtractorFunc, req crawlRequest) ([]*url.URL, error) {
+// linksToEdges converts each link to the Edge that is stored in the Result
+func linksToEdges(links []Link) []Edge {
+	edges := make([]Edge, len(links))
+	for i, l := range links {
+		edges[i] = Edge{
+			URL:      l.URL.String(),
+			Kind:     l.Kind,
+			NoFollow: l.NoFollow(),
+			Text:     l.Text,
+			Title:    l.Title,
+			Rel:      l.Rel,
+			Target:   l.Target,
+			Image:    l.Image,
+			Alt:      l.Alt,
+		}
+	}
+	return edges
+}
+
+// filterLinks applies the modifiers and filters to the URL of each link, keeping the first link of each kind to
+// each URL that passes the filters
+func filterLinks(links []Link, filters []URLFilterFunc, modifiers []URLModifyFunc) []Link {
+	urls := ModifyURLs(linkURLs(links), modifiers...)
+	keep := make(map[string]bool)
+	for _, u := range FilterURLs(urls, filters...) {
+		keep[u.String()] = true
+	}
+	seen := make(map[string]bool)
+	filtered := make([]Link, 0)
+	for i, l := range links {
+		l.URL = urls[i]
+		key := l.Kind.String() + " " + l.URL.String()
+		if !keep[l.URL.String()] || seen[key] {
+			continue
+		}
+		filtered = append(filtered, l)
+		seen[key] = true
+	}
+	return filtered
+}
+
+// followableLinks returns the links that the crawler should request from the page. Links that aren't
+// LinkKindNavigation are only returned if checkResources is true, and LinkKindForm links are never returned.
+func followableLinks(r crawlResponse, checkResources bool) []Link {
+	links := make([]Link, 0)
+	if r.noFollow {
+		return links
+	}
+	for _, l := range r.links {
+		if l.NoFollow() || l.Kind == LinkKindForm || (l.Kind != LinkKindNavigation && !checkResources) {
+			continue
+		}
+		links = append(links, l)
+	}
+	return links
+}
+
+// scrape the requests target, returning the links found on the page along with details of the response.
+// If the target redirects to a URL that has already been seen then the page isn't scraped. The URL that it redirects
+// to is modified by modifiers before it is checked, in the same way as the links that were queued. userAgent picks
+// the X-Robots-Tag headers that apply.
+func scrape(ctx context.Context, loader LoaderFunc, extractor ExtractorFunc, userAgent string, reqLog *requestLog, modifiers []URLModifyFunc, req crawlRequest) (res crawlResponse) {
+	res = crawlResponse{
+		request:  req,
+		finalURL: req.target,
+	}
+	start := time.Now()
+	defer func() {
+		res.latency = time.Since(start)
+	}()
+
 	// Load the page
-	reader, err := loader(req.target.String())
+	loaded, err := loader(ctx, req.target.String())
+	if loaded != nil {
+		res.statusCode = loaded.StatusCode
+		res.contentType = loaded.Header.Get("Content-Type")
+		if loaded.URL != nil {
+			res.finalURL = loaded.URL
+		}
+		res.redirects = loaded.Redirects
+	}
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		res.statusCode = statusErr.StatusCode
+	}
+	var contentTypeErr *ContentTypeError
+	if req.kind != LinkKindNavigation && errors.As(err, &contentTypeErr) {
+		// Resources aren't expected to be HTML, so loading them successfully is enough
+		return res
+	}
 	if err != nil {
-		return nil, err
+		res.err = newPageError(req.target.String(), err)
+		return res
+	}
+	defer loaded.Body.Close()
+
+	// Skip pages that we've already reached through another URL
+	seenURL := ModifyURL(res.finalURL, modifiers...)
+	if seenURL.String() != req.target.String() && !reqLog.MarkAsSeen(seenURL) {
+		res.duplicate = true
+		return res
+	}
+
+	// Resources are only checked, so there's no need to download them
+	if req.kind != LinkKindNavigation {
+		return res
 	}
-	defer reader.Close()
 
-	// Extract anchor tags from the page
-	urls, err := extractor(reader)
+	// Extract links from the page
+	body := &countingReader{r: loaded.Body}
+	doc, err := extractor(body)
+	res.size = body.n
 	if err != nil {
-		return nil, err
+		res.err = newPageError(req.target.String(), &ExtractError{Err: err})
+		return res
 	}
 
-	// Build the URLs as references from the Target
-	for i, u := range urls {
-		urls[i] = req.target.ResolveReference(u)
+	// Robots directives can come from either the page or the X-Robots-Tag header
+	res.noFollow = doc.NoFollow
+	res.noIndex = doc.NoIndex
+	for _, header := range loaded.Header.Values("X-Robots-Tag") {
+		noIndex, noFollow := parseRobotsHeader(header, userAgent)
+		res.noIndex = res.noIndex || noIndex
+		res.noFollow = res.noFollow || noFollow
 	}
 
-	return urls, nil
+	// Build the URLs as references from the page, which may have been redirected from the Target, or from the
+	// document base if the page declared one
+	base := res.finalURL
+	if doc.Base != nil {
+		base = res.finalURL.ResolveReference(doc.Base)
+	}
+	links := make([]Link, len(doc.Links))
+	for i, l := range doc.Links {
+		l.URL = base.ResolveReference(l.URL)
+		links[i] = l
+	}
+	res.links = links
+
+	return res
+}
+
+// countingReader counts the bytes read from the underlying io.Reader
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+// Read from the underlying io.Reader, counting the bytes read
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
 }
 
-// nextRequests builds the next crawlRequest for each URL based on the current request
-func nextRequests(req crawlRequest, urls []*url.URL) []crawlRequest {
+// nextRequests builds the next crawlRequest for each link based on the current request
+func nextRequests(req crawlRequest, links []Link) []crawlRequest {
 	reqs := make([]crawlRequest, 0)
-	for _, u := range urls {
-		reqs = append(reqs, req.next(u))
+	for _, l := range links {
+		reqs = append(reqs, req.next(l))
 	}
 	return reqs
 }
@@ -1,9 +1,12 @@
 package internal_test
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/jmwri/web-crawler/internal"
-	"io"
+	"io/ioutil"
+	"net/http"
 	"net/url"
 	"os"
 	"reflect"
@@ -11,22 +14,47 @@ import (
 	"testing"
 )
 
-func TestCrawl(t *testing.T) {
-	var loader internal.LoaderFunc = func(p string) (io.ReadCloser, error) {
+// testFileLoader loads pages from testdata. Pages on github.com are mapped to files prefixed with gh.
+var testFileLoader internal.LoaderFunc = func(ctx context.Context, p string) (*internal.Response, error) {
+	u, err := url.Parse(p)
+	if err != nil {
+		return nil, err
+	}
+
+	filename := u.Path
+	if u.Host == "github.com" {
+		filename = strings.ReplaceAll(u.Path, "/", "_")
+		filename = "gh" + filename + ".html"
+	}
+
+	f, err := os.Open(fmt.Sprintf("./testdata/html/%s", filename))
+	if err != nil {
+		return nil, err
+	}
+	return &internal.Response{Body: f}, nil
+}
+
+// testStringLoader loads pages from the given map of URL to HTML. Keys can be a host and path, ie "a/index.html", or
+// just a path, ie "/index.html", to load the page regardless of host.
+func testStringLoader(pages map[string]string) internal.LoaderFunc {
+	return func(ctx context.Context, p string) (*internal.Response, error) {
 		u, err := url.Parse(p)
 		if err != nil {
 			return nil, err
 		}
-
-		filename := u.Path
-		if u.Host == "github.com" {
-			filename = strings.ReplaceAll(u.Path, "/", "_")
-			filename = "gh" + filename + ".html"
+		body, ok := pages[u.Host+u.Path]
+		if !ok {
+			body, ok = pages[u.Path]
 		}
-
-		f, err := os.Open(fmt.Sprintf("./testdata/html/%s", filename))
-		return f, err
+		if !ok {
+			return nil, errors.New("not found")
+		}
+		return &internal.Response{Body: ioutil.NopCloser(strings.NewReader(body)), Header: http.Header{}}, nil
 	}
+}
+
+func TestCrawl(t *testing.T) {
+	loader := testFileLoader
 
 	type args struct {
 		loader    internal.LoaderFunc
@@ -258,10 +286,25 @@ func TestNewCrawlOptions(t *testing.T) {
 				workers:    1,
 			},
 			want: internal.CrawlOptions{
-				Target:     &url.URL{Path: "/"},
-				SameDomain: true,
-				MaxDepth:   0,
-				Workers:    1,
+				Target:   &url.URL{Path: "/"},
+				Scope:    internal.ScopeHost,
+				MaxDepth: 0,
+				Workers:  1,
+			},
+		},
+		{
+			name: "all domains",
+			args: args{
+				target:     &url.URL{Path: "/"},
+				sameDomain: false,
+				maxDepth:   0,
+				workers:    1,
+			},
+			want: internal.CrawlOptions{
+				Target:   &url.URL{Path: "/"},
+				Scope:    internal.ScopeAll,
+				MaxDepth: 0,
+				Workers:  1,
 			},
 		},
 		{
@@ -273,10 +316,10 @@ func TestNewCrawlOptions(t *testing.T) {
 				workers:    0,
 			},
 			want: internal.CrawlOptions{
-				Target:     &url.URL{Path: "/"},
-				SameDomain: true,
-				MaxDepth:   0,
-				Workers:    10,
+				Target:   &url.URL{Path: "/"},
+				Scope:    internal.ScopeHost,
+				MaxDepth: 0,
+				Workers:  10,
 			},
 		},
 	}
@@ -288,3 +331,384 @@ func TestNewCrawlOptions(t *testing.T) {
 		})
 	}
 }
+
+func TestCrawlContext_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Serve the index page, then block every other page until the crawl is cancelled
+	var loader internal.LoaderFunc = func(ctx context.Context, p string) (*internal.Response, error) {
+		if p == "https://localhost/index.html" {
+			return testFileLoader(ctx, p)
+		}
+		cancel()
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	o := internal.NewCrawlOptions(&url.URL{Scheme: "https", Host: "localhost", Path: "index.html"}, true, 0, 0)
+
+	got, err := internal.CrawlContext(ctx, loader, internal.HtmlTokenExtractor, o)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("CrawlContext() error = %v, want %v", err, context.Canceled)
+	}
+	wantIndex := []string{
+		"https://localhost/index.html",
+		"https://localhost/about.html",
+		"https://localhost/contact.html",
+	}
+	if !reflect.DeepEqual(got.URLs()["https://localhost/index.html"], wantIndex) {
+		t.Errorf("CrawlContext().URLs() got = %v, want index page with %v", got.URLs(), wantIndex)
+	}
+}
+
+func TestCrawlContext_AlreadyDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var loader internal.LoaderFunc = func(ctx context.Context, p string) (*internal.Response, error) {
+		t.Errorf("unexpected load of %s", p)
+		return nil, ctx.Err()
+	}
+	o := internal.NewCrawlOptions(&url.URL{Scheme: "https", Host: "localhost", Path: "index.html"}, true, 0, 0)
+
+	got, err := internal.CrawlContext(ctx, loader, internal.HtmlTokenExtractor, o)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("CrawlContext() error = %v, want %v", err, context.Canceled)
+	}
+	if len(got.URLs()) != 0 {
+		t.Errorf("CrawlContext().URLs() got = %v, want none", got.URLs())
+	}
+}
+
+func TestCrawlContext_OnPage(t *testing.T) {
+	var events []internal.PageEvent
+	o := internal.NewCrawlOptions(&url.URL{Scheme: "https", Host: "localhost", Path: "index.html"}, true, 0, 0)
+	o.OnPage = func(e internal.PageEvent) {
+		events = append(events, e)
+	}
+
+	_, err := internal.CrawlContext(context.Background(), testFileLoader, internal.HtmlTokenExtractor, o)
+	if err != nil {
+		t.Fatalf("CrawlContext() error = %v", err)
+	}
+
+	index := &url.URL{Scheme: "https", Host: "localhost", Path: "index.html"}
+	wantEvents := map[string]internal.PageEvent{
+		"https://localhost/index.html":   {URL: index, Depth: 1},
+		"https://localhost/about.html":   {Depth: 2, Origin: index},
+		"https://localhost/contact.html": {Depth: 2, Origin: index},
+	}
+	if len(events) != len(wantEvents) {
+		t.Fatalf("OnPage called %d times, want %d", len(events), len(wantEvents))
+	}
+	for _, e := range events {
+		want, ok := wantEvents[e.URL.String()]
+		if !ok {
+			t.Errorf("unexpected PageEvent for %s", e.URL)
+			continue
+		}
+		if e.Depth != want.Depth {
+			t.Errorf("PageEvent(%s).Depth = %d, want %d", e.URL, e.Depth, want.Depth)
+		}
+		if !reflect.DeepEqual(e.Origin, want.Origin) {
+			t.Errorf("PageEvent(%s).Origin = %v, want %v", e.URL, e.Origin, want.Origin)
+		}
+		if e.Err != nil {
+			t.Errorf("PageEvent(%s).Err = %v, want nil", e.URL, e.Err)
+		}
+		if len(e.Links) != 3 {
+			t.Errorf("PageEvent(%s).Links = %v, want 3 links", e.URL, e.Links)
+		}
+	}
+}
+
+func TestCrawl_NoFollow(t *testing.T) {
+	pages := map[string]string{
+		"/index.html":    `<a href="/followed.html">f</a><a href="/nofollow.html" rel="nofollow">n</a><a href="/meta.html">m</a><a href="/header.html">h</a>`,
+		"/meta.html":     `<meta name="robots" content="nofollow, noindex"><a href="/from-meta.html">a</a>`,
+		"/header.html":   `<a href="/from-header.html">a</a>`,
+		"/followed.html": ``,
+	}
+	loader := testStringLoader(pages)
+	headerLoader := func(ctx context.Context, p string) (*internal.Response, error) {
+		res, err := loader(ctx, p)
+		if err == nil && p == "https://localhost/header.html" {
+			res.Header.Set("X-Robots-Tag", "nofollow")
+		}
+		return res, err
+	}
+	o := internal.NewCrawlOptions(&url.URL{Scheme: "https", Host: "localhost", Path: "/index.html"}, true, 0, 1)
+
+	got, err := internal.Crawl(headerLoader, internal.HtmlTokenExtractor, o)
+	if err != nil {
+		t.Fatalf("Crawl() error = %v", err)
+	}
+
+	wantURLs := map[string][]string{
+		"https://localhost/index.html": {
+			"https://localhost/followed.html",
+			"https://localhost/nofollow.html",
+			"https://localhost/meta.html",
+			"https://localhost/header.html",
+		},
+		"https://localhost/followed.html": {},
+		"https://localhost/meta.html":     {"https://localhost/from-meta.html"},
+		"https://localhost/header.html":   {"https://localhost/from-header.html"},
+	}
+	if !reflect.DeepEqual(got.URLs(), wantURLs) {
+		t.Errorf("Crawl().URLs() got = %v, want %v", got.URLs(), wantURLs)
+	}
+
+	wantEdges := []internal.Edge{
+		{URL: "https://localhost/followed.html", Text: "f"},
+		{URL: "https://localhost/nofollow.html", NoFollow: true, Text: "n", Rel: []string{"nofollow"}},
+		{URL: "https://localhost/meta.html", Text: "m"},
+		{URL: "https://localhost/header.html", Text: "h"},
+	}
+	if edges := got.Pages()["https://localhost/index.html"].Edges; !reflect.DeepEqual(edges, wantEdges) {
+		t.Errorf("Page.Edges = %v, want %v", edges, wantEdges)
+	}
+	if meta := got.Pages()["https://localhost/meta.html"]; !meta.NoFollow || !meta.NoIndex {
+		t.Errorf("meta Page.NoFollow, NoIndex = %v, %v, want true, true", meta.NoFollow, meta.NoIndex)
+	}
+	if header := got.Pages()["https://localhost/header.html"]; !header.NoFollow || header.NoIndex {
+		t.Errorf("header Page.NoFollow, NoIndex = %v, %v, want true, false", header.NoFollow, header.NoIndex)
+	}
+}
+
+func TestCrawl_XRobotsTagUserAgent(t *testing.T) {
+	tests := []struct {
+		name         string
+		header       string
+		wantNoIndex  bool
+		wantNoFollow bool
+	}{
+		{name: "no user agent", header: "nofollow", wantNoFollow: true},
+		{name: "other user agent", header: "otherbot: noindex, nofollow"},
+		{name: "matching user agent", header: "Web-Crawler: nofollow", wantNoFollow: true},
+		{name: "directive with value", header: "noindex, unavailable_after: 25 Jun 2010 15:00:00 PST", wantNoIndex: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loader := testStringLoader(map[string]string{"/index.html": `<a href="/about.html">a</a>`, "/about.html": ``})
+			headerLoader := func(ctx context.Context, p string) (*internal.Response, error) {
+				res, err := loader(ctx, p)
+				if err == nil {
+					res.Header.Set("X-Robots-Tag", tt.header)
+				}
+				return res, err
+			}
+			o := internal.NewCrawlOptions(&url.URL{Scheme: "https", Host: "localhost", Path: "/index.html"}, true, 0, 1)
+			o.UserAgent = "web-crawler/1.0"
+
+			got, err := internal.Crawl(headerLoader, internal.HtmlTokenExtractor, o)
+			if err != nil {
+				t.Fatalf("Crawl() error = %v", err)
+			}
+			index := got.Pages()["https://localhost/index.html"]
+			if index.NoIndex != tt.wantNoIndex || index.NoFollow != tt.wantNoFollow {
+				t.Errorf("Page.NoIndex, NoFollow = %v, %v, want %v, %v", index.NoIndex, index.NoFollow, tt.wantNoIndex, tt.wantNoFollow)
+			}
+			if _, followed := got.Pages()["https://localhost/about.html"]; followed == tt.wantNoFollow {
+				t.Errorf("Crawl() followed /about.html = %v, want %v", followed, !tt.wantNoFollow)
+			}
+		})
+	}
+}
+
+func TestCrawl_Base(t *testing.T) {
+	pages := map[string]string{
+		"/docs/index.html": `<head><base href="/static/"></head><a href="page.html">p</a><a href="/root.html">r</a>`,
+		"/absolute.html":   `<head><base href="https://other.localhost/a/"><base href="/ignored/"></head><a href="b.html">b</a>`,
+		"/none.html":       `<a href="c.html">c</a>`,
+	}
+	tests := []struct {
+		name   string
+		target *url.URL
+		want   []string
+	}{
+		{
+			name:   "relative base",
+			target: &url.URL{Scheme: "https", Host: "localhost", Path: "/docs/index.html"},
+			want:   []string{"https://localhost/static/page.html", "https://localhost/root.html"},
+		},
+		{
+			name:   "absolute base",
+			target: &url.URL{Scheme: "https", Host: "localhost", Path: "/absolute.html"},
+			want:   []string{"https://other.localhost/a/b.html"},
+		},
+		{
+			name:   "no base",
+			target: &url.URL{Scheme: "https", Host: "localhost", Path: "/none.html"},
+			want:   []string{"https://localhost/c.html"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := internal.NewCrawlOptions(tt.target, false, 1, 1)
+			got, err := internal.Crawl(testStringLoader(pages), internal.HtmlTokenExtractor, o)
+			if err != nil {
+				t.Fatalf("Crawl() error = %v", err)
+			}
+			if links := got.URLs()[tt.target.String()]; !reflect.DeepEqual(links, tt.want) {
+				t.Errorf("Crawl().URLs()[%s] = %v, want %v", tt.target, links, tt.want)
+			}
+		})
+	}
+}
+
+func TestCrawl_Modifiers(t *testing.T) {
+	pages := map[string]string{
+		"/index.html": `<a href="/a/b/%2E">dot</a><a href="/a/b">b</a>`,
+		"/a/b":        ``,
+	}
+	o := internal.NewCrawlOptions(&url.URL{Scheme: "https", Host: "localhost", Path: "/index.html"}, true, 0, 1)
+	o.Modifiers = internal.RFC3986Modifiers()
+
+	got, err := internal.Crawl(testStringLoader(pages), internal.HtmlTokenExtractor, o)
+	if err != nil {
+		t.Fatalf("Crawl() error = %v", err)
+	}
+	// Links are resolved against the page, which removes a plain "." segment, so it is encoded to reach the
+	// modifiers. /a/b/%2E becomes /a/b/. once its encoding is normalized, /a/b/ once its dot segments are removed,
+	// and then /a/b once its trailing slash is removed.
+	want := map[string][]string{
+		"https://localhost/index.html": {"https://localhost/a/b"},
+		"https://localhost/a/b":        {},
+	}
+	if !reflect.DeepEqual(got.URLs(), want) {
+		t.Errorf("Crawl().URLs() got = %v, want %v", got.URLs(), want)
+	}
+}
+
+func TestCrawl_CheckResources(t *testing.T) {
+	pages := map[string]string{
+		"/index.html": `<link rel="stylesheet" href="/style.css"><img src="/logo.png"><script src="/missing.js"></script><a href="/about.html">about</a><form action="/subscribe"></form>`,
+		"/about.html": ``,
+		"/style.css":  `<a href="/from-css.html">not scraped</a>`,
+	}
+	stringLoader := testStringLoader(pages)
+	var loader internal.LoaderFunc = func(ctx context.Context, p string) (*internal.Response, error) {
+		if p == "https://localhost/logo.png" {
+			res := &internal.Response{Body: http.NoBody, StatusCode: 200, Header: http.Header{"Content-Type": {"image/png"}}}
+			return res, &internal.ContentTypeError{ContentType: "image/png"}
+		}
+		return stringLoader(ctx, p)
+	}
+
+	tests := []struct {
+		name           string
+		checkResources bool
+		wantPages      map[string]internal.LinkKind
+		wantFailed     []string
+	}{
+		{
+			name:           "only navigation",
+			checkResources: false,
+			wantPages: map[string]internal.LinkKind{
+				"https://localhost/index.html": internal.LinkKindNavigation,
+				"https://localhost/about.html": internal.LinkKindNavigation,
+			},
+			wantFailed: []string{},
+		},
+		{
+			name:           "check resources",
+			checkResources: true,
+			wantPages: map[string]internal.LinkKind{
+				"https://localhost/index.html": internal.LinkKindNavigation,
+				"https://localhost/about.html": internal.LinkKindNavigation,
+				"https://localhost/style.css":  internal.LinkKindStylesheet,
+				"https://localhost/logo.png":   internal.LinkKindImage,
+				"https://localhost/missing.js": internal.LinkKindScript,
+			},
+			wantFailed: []string{"https://localhost/missing.js"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := internal.NewCrawlOptions(&url.URL{Scheme: "https", Host: "localhost", Path: "/index.html"}, true, 0, 1)
+			o.CheckResources = tt.checkResources
+
+			got, err := internal.Crawl(loader, internal.HtmlTokenExtractor, o)
+			if err != nil {
+				t.Fatalf("Crawl() error = %v", err)
+			}
+			gotPages := make(map[string]internal.LinkKind)
+			for u, p := range got.Pages() {
+				gotPages[u] = p.Kind
+			}
+			if !reflect.DeepEqual(gotPages, tt.wantPages) {
+				t.Errorf("Crawl().Pages() kinds = %v, want %v", gotPages, tt.wantPages)
+			}
+			if !reflect.DeepEqual(got.FailedURLs(), tt.wantFailed) {
+				t.Errorf("Crawl().FailedURLs() = %v, want %v", got.FailedURLs(), tt.wantFailed)
+			}
+			if edges := got.Pages()["https://localhost/index.html"].Edges; len(edges) != 5 {
+				t.Errorf("Page.Edges = %v, want 5 edges", edges)
+			}
+			if css, ok := got.Pages()["https://localhost/style.css"]; ok && len(css.Edges) != 0 {
+				t.Errorf("stylesheet Page.Edges = %v, want none", css.Edges)
+			}
+		})
+	}
+}
+
+func TestCrawl_Patterns(t *testing.T) {
+	o := internal.NewCrawlOptions(&url.URL{Scheme: "https", Host: "localhost", Path: "index.html"}, true, 0, 0)
+	o.Exclude = []internal.URLPattern{internal.GlobPattern("contact.html")}
+
+	got, err := internal.Crawl(testFileLoader, internal.HtmlTokenExtractor, o)
+	if err != nil {
+		t.Fatalf("Crawl() error = %v", err)
+	}
+	wantURLs := map[string][]string{
+		"https://localhost/index.html": {"https://localhost/index.html", "https://localhost/about.html"},
+		"https://localhost/about.html": {"https://localhost/index.html", "https://localhost/about.html"},
+	}
+	if !reflect.DeepEqual(got.URLs(), wantURLs) {
+		t.Errorf("Crawl().URLs() got = %v, want %v", got.URLs(), wantURLs)
+	}
+	wantExcluded := map[string]string{"https://localhost/contact.html": "matched exclude glob:contact.html"}
+	if !reflect.DeepEqual(got.Excluded(), wantExcluded) {
+		t.Errorf("Crawl().Excluded() got = %v, want %v", got.Excluded(), wantExcluded)
+	}
+}
+
+func TestCrawl_MultipleSeeds(t *testing.T) {
+	pages := map[string]string{
+		"a/index.html": `<a href="/1">1</a><a href="https://b/1">b1</a>`,
+		"a/1":          `<a href="/2">2</a>`,
+		"a/2":          ``,
+		"b/index.html": `<a href="/1">1</a>`,
+		"b/1":          ``,
+	}
+	o := internal.NewCrawlOptions(&url.URL{Scheme: "https", Host: "a", Path: "/index.html"}, true, 2, 0)
+	o.Seeds = []*url.URL{{Scheme: "https", Host: "b", Path: "/index.html"}}
+
+	got, err := internal.Crawl(testStringLoader(pages), internal.HtmlTokenExtractor, o)
+	if err != nil {
+		t.Fatalf("Crawl() error = %v", err)
+	}
+	type seedDepth struct {
+		seed  string
+		depth int
+	}
+	// Each seed has its own depth, and links to another seed are outside of the scope of the page they are on
+	want := map[string]seedDepth{
+		"https://a/index.html": {seed: "https://a/index.html", depth: 1},
+		"https://a/1":          {seed: "https://a/index.html", depth: 2},
+		"https://b/index.html": {seed: "https://b/index.html", depth: 1},
+		"https://b/1":          {seed: "https://b/index.html", depth: 2},
+	}
+	gotPages := make(map[string]seedDepth)
+	for u, p := range got.Pages() {
+		gotPages[u] = seedDepth{seed: p.Seed, depth: p.Depth}
+	}
+	if !reflect.DeepEqual(gotPages, want) {
+		t.Errorf("Crawl().Pages() got = %v, want %v", gotPages, want)
+	}
+	wantLinks := []string{"https://a/1"}
+	if links := got.Pages()["https://a/index.html"].Links(); !reflect.DeepEqual(links, wantLinks) {
+		t.Errorf("Crawl().Pages()[https://a/index.html].Links() = %v, want %v", links, wantLinks)
+	}
+}
@@ -0,0 +1,130 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// ErrorKind categorises why a page could not be crawled
+type ErrorKind int
+
+const (
+	// ErrorKindLoad is used when the page could not be loaded for a reason that isn't otherwise categorised
+	ErrorKindLoad ErrorKind = iota
+	// ErrorKindNetwork is used when the page could not be loaded because of a network error
+	ErrorKindNetwork
+	// ErrorKindTimeout is used when loading the page timed out
+	ErrorKindTimeout
+	// ErrorKindCancelled is used when the crawl was cancelled while the page was loading
+	ErrorKindCancelled
+	// ErrorKindHTTPStatus is used when the page responded with an unsuccessful HTTP status
+	ErrorKindHTTPStatus
+	// ErrorKindExtract is used when links could not be extracted from the page
+	ErrorKindExtract
+	// ErrorKindNonHTML is used when the page was skipped because it isn't HTML
+	ErrorKindNonHTML
+)
+
+// String returns a human readable name for the ErrorKind
+func (k ErrorKind) String() string {
+	switch k {
+	case ErrorKindNetwork:
+		return "network error"
+	case ErrorKindTimeout:
+		return "timeout"
+	case ErrorKindCancelled:
+		return "cancelled"
+	case ErrorKindHTTPStatus:
+		return "http status"
+	case ErrorKindExtract:
+		return "extract error"
+	case ErrorKindNonHTML:
+		return "non-HTML resource"
+	default:
+		return "load error"
+	}
+}
+
+// PageError describes why a page could not be crawled
+type PageError struct {
+	// URL is the URL of the page that failed
+	URL string
+	// Kind categorises the error
+	Kind ErrorKind
+	// Attempts is the number of attempts that were made to load the page
+	Attempts int
+	// Err is the underlying error
+	Err error
+}
+
+// Error describes the failed page
+func (e *PageError) Error() string {
+	return fmt.Sprintf("%s: %s after %d attempt(s): %s", e.URL, e.Kind, e.Attempts, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *PageError) Unwrap() error {
+	return e.Err
+}
+
+// newPageError wraps err in a PageError for the given URL. It returns nil if err is nil.
+func newPageError(u string, err error) *PageError {
+	if err == nil {
+		return nil
+	}
+	attempts := 1
+	var attemptsErr *AttemptsError
+	if errors.As(err, &attemptsErr) {
+		attempts = attemptsErr.Attempts
+	}
+	return &PageError{
+		URL:      u,
+		Kind:     errorKind(err),
+		Attempts: attempts,
+		Err:      err,
+	}
+}
+
+// errorKind categorises the given error
+func errorKind(err error) ErrorKind {
+	var statusErr *StatusError
+	var extractErr *ExtractError
+	var contentTypeErr *ContentTypeError
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.Canceled):
+		return ErrorKindCancelled
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrorKindTimeout
+	case errors.As(err, &statusErr):
+		return ErrorKindHTTPStatus
+	case errors.As(err, &extractErr):
+		return ErrorKindExtract
+	case errors.As(err, &contentTypeErr):
+		return ErrorKindNonHTML
+	case errors.As(err, &netErr):
+		if netErr.Timeout() {
+			return ErrorKindTimeout
+		}
+		return ErrorKindNetwork
+	default:
+		return ErrorKindLoad
+	}
+}
+
+// ExtractError wraps an error returned by an ExtractorFunc
+type ExtractError struct {
+	Err error
+}
+
+// Error describes the extraction failure
+func (e *ExtractError) Error() string {
+	return fmt.Sprintf("failed to extract links: %s", e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *ExtractError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,80 @@
+package internal_test
+
+import (
+	"context"
+	"errors"
+	"github.com/jmwri/web-crawler/internal"
+	"io"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestCrawl_Errors(t *testing.T) {
+	extractErr := errors.New("bad html")
+	var extractor internal.ExtractorFunc = func(r io.Reader) (internal.Document, error) {
+		return internal.Document{}, extractErr
+	}
+	var loader internal.LoaderFunc = func(ctx context.Context, p string) (*internal.Response, error) {
+		switch p {
+		case "https://localhost/status":
+			return nil, &internal.AttemptsError{Attempts: 3, Err: &internal.StatusError{StatusCode: 404}}
+		case "https://localhost/image":
+			return nil, &internal.ContentTypeError{ContentType: "image/png"}
+		case "https://localhost/extract":
+			return testFileLoader(ctx, "https://localhost/index.html")
+		default:
+			return nil, errors.New("not found")
+		}
+	}
+
+	o := internal.NewCrawlOptions(&url.URL{Scheme: "https", Host: "localhost", Path: "/status"}, true, 0, 1)
+	o.Seeds = []*url.URL{
+		{Scheme: "https", Host: "localhost", Path: "/extract"},
+		{Scheme: "https", Host: "localhost", Path: "/load"},
+		{Scheme: "https", Host: "localhost", Path: "/image"},
+	}
+	got, err := internal.Crawl(loader, extractor, o)
+	if err != nil {
+		t.Fatalf("Crawl() error = %v", err)
+	}
+
+	wantFailed := []string{
+		"https://localhost/extract",
+		"https://localhost/image",
+		"https://localhost/load",
+		"https://localhost/status",
+	}
+	if !reflect.DeepEqual(got.FailedURLs(), wantFailed) {
+		t.Errorf("Crawl().FailedURLs() got = %v, want %v", got.FailedURLs(), wantFailed)
+	}
+
+	tests := []struct {
+		url          string
+		wantKind     internal.ErrorKind
+		wantAttempts int
+		wantErr      error
+	}{
+		{url: "https://localhost/status", wantKind: internal.ErrorKindHTTPStatus, wantAttempts: 3},
+		{url: "https://localhost/extract", wantKind: internal.ErrorKindExtract, wantAttempts: 1, wantErr: extractErr},
+		{url: "https://localhost/load", wantKind: internal.ErrorKindLoad, wantAttempts: 1},
+		{url: "https://localhost/image", wantKind: internal.ErrorKindNonHTML, wantAttempts: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			pageErr, ok := got.Errors()[tt.url]
+			if !ok {
+				t.Fatalf("Crawl().Errors() missing %s", tt.url)
+			}
+			if pageErr.Kind != tt.wantKind {
+				t.Errorf("PageError.Kind = %s, want %s", pageErr.Kind, tt.wantKind)
+			}
+			if pageErr.Attempts != tt.wantAttempts {
+				t.Errorf("PageError.Attempts = %d, want %d", pageErr.Attempts, tt.wantAttempts)
+			}
+			if tt.wantErr != nil && !errors.Is(pageErr, tt.wantErr) {
+				t.Errorf("PageError = %v, want to wrap %v", pageErr, tt.wantErr)
+			}
+		})
+	}
+}
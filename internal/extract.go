@@ -6,57 +6,338 @@ import (
 	"golang.org/x/net/html/atom"
 	"io"
 	"net/url"
+	"strings"
 )
 
 // ExtractorFunc extracts links from the given io.Reader
-type ExtractorFunc func(r io.Reader) ([]*url.URL, error)
+type ExtractorFunc func(r io.Reader) (Document, error)
+
+// Document contains everything extracted from a page
+type Document struct {
+	// Base is the href of the page's first <base> tag, which links are relative to. It is nil if there isn't one.
+	Base *url.URL
+	// Links are the links found on the page, in the order they were found
+	Links []Link
+	// NoFollow is true if the page asked for none of its links to be followed with a robots meta tag
+	NoFollow bool
+	// NoIndex is true if the page asked not to be indexed with a robots meta tag
+	NoIndex bool
+}
+
+// LinkKind describes the type of resource that a link points to
+type LinkKind int
+
+const (
+	// LinkKindNavigation is a link to another page, from an <a> or <area>
+	LinkKindNavigation LinkKind = iota
+	// LinkKindStylesheet is a <link rel="stylesheet">
+	LinkKindStylesheet
+	// LinkKindImage is an <img>, a <source> in a <picture>, a <video> poster or a <link rel="icon">
+	LinkKindImage
+	// LinkKindScript is a <script src>
+	LinkKindScript
+	// LinkKindFrame is an <iframe> or <frame>
+	LinkKindFrame
+	// LinkKindForm is the action of a <form>
+	LinkKindForm
+	// LinkKindMedia is a <video>, <audio>, or one of their <source> or <track> elements
+	LinkKindMedia
+	// LinkKindOther is any other <link>, ie rel="canonical" or rel="preload"
+	LinkKindOther
+)
+
+// String describes the LinkKind
+func (k LinkKind) String() string {
+	switch k {
+	case LinkKindNavigation:
+		return "navigation"
+	case LinkKindStylesheet:
+		return "stylesheet"
+	case LinkKindImage:
+		return "image"
+	case LinkKindScript:
+		return "script"
+	case LinkKindFrame:
+		return "frame"
+	case LinkKindForm:
+		return "form"
+	case LinkKindMedia:
+		return "media"
+	case LinkKindOther:
+		return "other"
+	default:
+		return "unknown"
+	}
+}
+
+// Link is a single link found on a page
+type Link struct {
+	// URL is the URL that the link points to, as written on the page
+	URL *url.URL
+	// Kind is the type of resource that the link points to
+	Kind LinkKind
+	// Rel contains each value of the rel attribute, in lower case
+	Rel []string
+	// Text is the text inside an <a>, with whitespace collapsed
+	Text string
+	// Title is the title attribute
+	Title string
+	// Target is the target attribute, ie "_blank"
+	Target string
+	// Image is true if an <a> contains an <img>
+	Image bool
+	// Alt is the alt text of the first <img> inside an <a>
+	Alt string
+}
+
+// NoFollow returns whether the link asked not to be followed, with rel nofollow, ugc or sponsored
+func (l Link) NoFollow() bool {
+	for _, rel := range l.Rel {
+		if rel == "nofollow" || rel == "ugc" || rel == "sponsored" {
+			return true
+		}
+	}
+	return false
+}
 
 // HtmlTokenExtractor uses html.Tokenizer to extract links
-func HtmlTokenExtractor(r io.Reader) ([]*url.URL, error) {
+func HtmlTokenExtractor(r io.Reader) (Document, error) {
 	t := html.NewTokenizer(r)
 
-	links := make([]*url.URL, 0)
-	seenLinks := map[string]bool{}
+	doc := Document{Links: make([]Link, 0)}
+	// seenLinks maps the kind and href of each link to its index in doc.Links
+	seenLinks := map[string]int{}
+	// anchor is the index in doc.Links of the <a> that we're inside, or -1. Its text is collected in anchorText.
+	anchor := -1
+	anchorText := &strings.Builder{}
+	closeAnchor := func() {
+		if anchor >= 0 {
+			doc.Links[anchor].Text = strings.Join(strings.Fields(anchorText.String()), " ")
+		}
+		anchor = -1
+		anchorText.Reset()
+	}
 
 	for {
 		tokenType := t.Next()
 		if tokenType == html.ErrorToken {
 			if errors.Is(t.Err(), io.EOF) {
-				return links, nil
+				closeAnchor()
+				return doc, nil
 			}
-			return nil, t.Err()
+			return Document{}, t.Err()
 		}
 		token := t.Token()
 
-		// Only searching for anchor tags, which aren't usually self closing
-		if token.Type != html.StartTagToken || token.DataAtom != atom.A {
+		switch token.Type {
+		case html.TextToken:
+			if anchor >= 0 {
+				anchorText.WriteString(token.Data)
+			}
+			continue
+		case html.EndTagToken:
+			if token.DataAtom == atom.A {
+				closeAnchor()
+			}
+			continue
+		case html.StartTagToken, html.SelfClosingTagToken:
+		default:
 			continue
 		}
-		link := hrefValue(token)
-		if link == "" {
+		if token.DataAtom == atom.A {
+			// Anchors can't be nested, so a new one closes the last
+			closeAnchor()
+		}
+		if token.DataAtom == atom.Img && anchor >= 0 {
+			if !doc.Links[anchor].Image {
+				doc.Links[anchor].Alt = strings.TrimSpace(attrValue(token, atom.Alt))
+			}
+			doc.Links[anchor].Image = true
+		}
+		if token.DataAtom == atom.Base {
+			// Only the first base with an href applies
+			if href := attrValue(token, atom.Href); href != "" && doc.Base == nil {
+				if base, err := url.Parse(strings.TrimSpace(href)); err == nil {
+					doc.Base = base
+				}
+			}
 			continue
 		}
-		// Skip the link if we've already seen it
-		if seen, ok := seenLinks[link]; ok && seen {
+		if token.DataAtom == atom.Meta {
+			if strings.EqualFold(attrValue(token, atom.Name), "robots") {
+				noIndex, noFollow := parseRobotsDirectives(attrValue(token, atom.Content))
+				doc.NoIndex = doc.NoIndex || noIndex
+				doc.NoFollow = doc.NoFollow || noFollow
+			}
 			continue
 		}
-		parsedUrl, err := url.Parse(link)
-		if err != nil {
-			// Skip links we're unable to parse
+
+		var rel []string
+		if relAttr := attrValue(token, atom.Rel); relAttr != "" {
+			rel = strings.Fields(strings.ToLower(relAttr))
+		}
+		for _, tl := range tokenLinks(token) {
+			key := tl.kind.String() + " " + tl.href
+			// Skip the link if we've already seen it, unless it can now be followed
+			if i, ok := seenLinks[key]; ok {
+				if doc.Links[i].NoFollow() && !(Link{Rel: rel}).NoFollow() {
+					doc.Links[i].Rel = rel
+				}
+				continue
+			}
+			parsedUrl, err := url.Parse(tl.href)
+			if err != nil {
+				// Skip links we're unable to parse
+				continue
+			}
+			doc.Links = append(doc.Links, Link{
+				URL:    parsedUrl,
+				Kind:   tl.kind,
+				Rel:    rel,
+				Title:  strings.TrimSpace(attrValue(token, atom.Title)),
+				Target: attrValue(token, atom.Target),
+			})
+			seenLinks[key] = len(doc.Links) - 1
+			if token.DataAtom == atom.A && token.Type == html.StartTagToken {
+				anchor = len(doc.Links) - 1
+			}
+		}
+	}
+}
+
+// tokenLink is a link found in the attributes of a single token
+type tokenLink struct {
+	href string
+	kind LinkKind
+}
+
+// tokenLinks returns the links in the attributes of the given token, skipping any that are empty
+func tokenLinks(t html.Token) []tokenLink {
+	var links []tokenLink
+	add := func(kind LinkKind, hrefs ...string) {
+		for _, href := range hrefs {
+			href = strings.TrimSpace(href)
+			if href != "" {
+				links = append(links, tokenLink{href: href, kind: kind})
+			}
+		}
+	}
+	switch t.DataAtom {
+	case atom.A, atom.Area:
+		add(LinkKindNavigation, attrValue(t, atom.Href))
+	case atom.Link:
+		add(linkRelKind(attrValue(t, atom.Rel)), attrValue(t, atom.Href))
+	case atom.Img:
+		add(LinkKindImage, attrValue(t, atom.Src))
+		add(LinkKindImage, parseSrcset(attrValue(t, atom.Srcset))...)
+	case atom.Script:
+		add(LinkKindScript, attrValue(t, atom.Src))
+	case atom.Iframe, atom.Frame:
+		add(LinkKindFrame, attrValue(t, atom.Src))
+	case atom.Source:
+		// A source with a src is inside a video or audio, whereas a srcset is inside a picture
+		add(LinkKindMedia, attrValue(t, atom.Src))
+		add(LinkKindImage, parseSrcset(attrValue(t, atom.Srcset))...)
+	case atom.Video:
+		add(LinkKindMedia, attrValue(t, atom.Src))
+		add(LinkKindImage, attrValue(t, atom.Poster))
+	case atom.Audio, atom.Track:
+		add(LinkKindMedia, attrValue(t, atom.Src))
+	case atom.Form:
+		add(LinkKindForm, attrValue(t, atom.Action))
+	}
+	return links
+}
+
+// linkRelKind returns the kind of resource that a <link> tag with the given rel attribute points to
+func linkRelKind(rel string) LinkKind {
+	for _, r := range strings.Fields(strings.ToLower(rel)) {
+		switch r {
+		case "stylesheet":
+			return LinkKindStylesheet
+		case "icon", "apple-touch-icon", "mask-icon":
+			return LinkKindImage
+		}
+	}
+	return LinkKindOther
+}
+
+// parseSrcset returns the URL of each image candidate in a srcset attribute, ie "a.png 1x, b.png 2x"
+func parseSrcset(srcset string) []string {
+	var urls []string
+	for {
+		srcset = strings.TrimLeft(srcset, " \t\n\r\f,")
+		if srcset == "" {
+			return urls
+		}
+		end := strings.IndexAny(srcset, " \t\n\r\f")
+		if end == -1 {
+			end = len(srcset)
+		}
+		u := srcset[:end]
+		srcset = srcset[end:]
+		if strings.HasSuffix(u, ",") {
+			// A trailing comma means there are no descriptors
+			urls = append(urls, strings.TrimRight(u, ","))
 			continue
 		}
-		links = append(links, parsedUrl)
-		seenLinks[link] = true
+		urls = append(urls, u)
+		// Skip the descriptors
+		if i := strings.IndexByte(srcset, ','); i >= 0 {
+			srcset = srcset[i+1:]
+		} else {
+			srcset = ""
+		}
 	}
 }
 
-// hrefValue returns the value of the href attribute
-func hrefValue(t html.Token) string {
+// attrValue returns the value of the given attribute
+func attrValue(t html.Token, a atom.Atom) string {
 	for _, attr := range t.Attr {
 		attrAtom := atom.Lookup([]byte(attr.Key))
-		if attrAtom == atom.Href {
+		if attrAtom == a {
 			return attr.Val
 		}
 	}
 	return ""
 }
+
+// robotsTagDirectives are the directives that take a value after a colon, so aren't mistaken for a user agent
+var robotsTagDirectives = map[string]bool{
+	"unavailable_after": true,
+	"max-snippet":       true,
+	"max-image-preview": true,
+	"max-video-preview": true,
+}
+
+// parseRobotsHeader parses an X-Robots-Tag header. A header that is scoped to a user agent, ie
+// "otherbot: noindex, nofollow", is ignored unless the agent matches the product token of userAgent.
+func parseRobotsHeader(header string, userAgent string) (noIndex bool, noFollow bool) {
+	if i := strings.Index(header, ":"); i >= 0 {
+		agent := strings.ToLower(strings.TrimSpace(header[:i]))
+		if !strings.ContainsAny(agent, ", ") && !robotsTagDirectives[agent] {
+			if agent != robotsProductToken(userAgent) {
+				return false, false
+			}
+			header = header[i+1:]
+		}
+	}
+	return parseRobotsDirectives(header)
+}
+
+// parseRobotsDirectives parses the comma separated directives from a robots meta tag or X-Robots-Tag header
+func parseRobotsDirectives(directives string) (noIndex bool, noFollow bool) {
+	for _, d := range strings.Split(directives, ",") {
+		d = strings.ToLower(strings.TrimSpace(d))
+		switch d {
+		case "noindex":
+			noIndex = true
+		case "nofollow":
+			noFollow = true
+		case "none":
+			noIndex = true
+			noFollow = true
+		}
+	}
+	return noIndex, noFollow
+}
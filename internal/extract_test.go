@@ -6,6 +6,7 @@ import (
 	"net/url"
 	"os"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -133,8 +134,12 @@ func testExtractorFunc(t *testing.T, f internal.ExtractorFunc) {
 				t.Errorf("Extract() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
-			if !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("Extract() got = %v, want %v", got, tt.want)
+			gotURLs := make([]*url.URL, len(got.Links))
+			for i, l := range got.Links {
+				gotURLs[i] = l.URL
+			}
+			if !reflect.DeepEqual(gotURLs, tt.want) {
+				t.Errorf("Extract() got = %v, want %v", gotURLs, tt.want)
 			}
 		})
 	}
@@ -143,3 +148,182 @@ func testExtractorFunc(t *testing.T, f internal.ExtractorFunc) {
 func TestHtmlTokenExtractor(t *testing.T) {
 	testExtractorFunc(t, internal.HtmlTokenExtractor)
 }
+
+func TestHtmlTokenExtractor_Robots(t *testing.T) {
+	tests := []struct {
+		name         string
+		html         string
+		wantNoFollow []bool
+		wantPage     internal.Document
+	}{
+		{
+			name:         "rel nofollow, ugc and sponsored",
+			html:         `<a href="/a" rel="nofollow">a</a><a href="/b" rel="UGC">b</a><a href="/c" rel="noopener sponsored">c</a><a href="/d" rel="noopener">d</a>`,
+			wantNoFollow: []bool{true, true, true, false},
+		},
+		{
+			name:         "followed duplicate wins",
+			html:         `<a href="/a" rel="nofollow">a</a><a href="/a">a</a>`,
+			wantNoFollow: []bool{false},
+		},
+		{
+			name:         "meta nofollow",
+			html:         `<head><meta name="robots" content="nofollow"></head><a href="/a">a</a>`,
+			wantNoFollow: []bool{false},
+			wantPage:     internal.Document{NoFollow: true},
+		},
+		{
+			name:         "meta noindex",
+			html:         `<head><meta name="ROBOTS" content="noarchive, noindex" /></head><a href="/a">a</a>`,
+			wantNoFollow: []bool{false},
+			wantPage:     internal.Document{NoIndex: true},
+		},
+		{
+			name:         "meta none",
+			html:         `<head><meta name="robots" content="none"></head>`,
+			wantNoFollow: []bool{},
+			wantPage:     internal.Document{NoFollow: true, NoIndex: true},
+		},
+		{
+			name:         "meta for another crawler",
+			html:         `<head><meta name="otherbot" content="none"></head>`,
+			wantNoFollow: []bool{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := internal.HtmlTokenExtractor(strings.NewReader(tt.html))
+			if err != nil {
+				t.Fatalf("HtmlTokenExtractor() error = %v", err)
+			}
+			gotNoFollow := make([]bool, len(got.Links))
+			for i, l := range got.Links {
+				gotNoFollow[i] = l.NoFollow()
+			}
+			if !reflect.DeepEqual(gotNoFollow, tt.wantNoFollow) {
+				t.Errorf("HtmlTokenExtractor() Link.NoFollow() = %v, want %v", gotNoFollow, tt.wantNoFollow)
+			}
+			if got.NoFollow != tt.wantPage.NoFollow || got.NoIndex != tt.wantPage.NoIndex {
+				t.Errorf("HtmlTokenExtractor() NoFollow, NoIndex = %v, %v, want %v, %v", got.NoFollow, got.NoIndex, tt.wantPage.NoFollow, tt.wantPage.NoIndex)
+			}
+		})
+	}
+}
+
+func TestHtmlTokenExtractor_Base(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want *url.URL
+	}{
+		{
+			name: "no base",
+			html: `<a href="/a">a</a>`,
+			want: nil,
+		},
+		{
+			name: "relative base",
+			html: `<head><base href="/docs/"></head>`,
+			want: &url.URL{Path: "/docs/"},
+		},
+		{
+			name: "first base wins",
+			html: `<head><base target="_blank"><base href="https://localhost/a/"><base href="/b/"></head>`,
+			want: &url.URL{Scheme: "https", Host: "localhost", Path: "/a/"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := internal.HtmlTokenExtractor(strings.NewReader(tt.html))
+			if err != nil {
+				t.Fatalf("HtmlTokenExtractor() error = %v", err)
+			}
+			if !reflect.DeepEqual(got.Base, tt.want) {
+				t.Errorf("HtmlTokenExtractor() Base = %v, want %v", got.Base, tt.want)
+			}
+		})
+	}
+}
+
+func TestHtmlTokenExtractor_Kinds(t *testing.T) {
+	html := `<html><head>
+<link rel="stylesheet" href="/style.css">
+<link rel="icon" href="/favicon.ico">
+<link rel="canonical" href="/canonical">
+<script src="/app.js"></script>
+<script>inline()</script>
+</head><body>
+<a href="/page">page</a>
+<map><area href="/area"></map>
+<img src="/logo.png" srcset="/logo-1x.png 1x, /logo-2x.png 2x">
+<img src="/logo.png" alt="duplicate">
+<picture><source srcset="/wide.png"></picture>
+<iframe src="/frame"></iframe>
+<video src="/clip.mp4" poster="/poster.png"><source src="/clip.webm"><track src="/subs.vtt"></video>
+<audio src="/sound.mp3"></audio>
+<form action="/search"></form>
+<form action=""></form>
+<a href="/logo.png">image as a page</a>
+</body></html>`
+	type link struct {
+		URL  string
+		Kind internal.LinkKind
+	}
+	want := []link{
+		{"/style.css", internal.LinkKindStylesheet},
+		{"/favicon.ico", internal.LinkKindImage},
+		{"/canonical", internal.LinkKindOther},
+		{"/app.js", internal.LinkKindScript},
+		{"/page", internal.LinkKindNavigation},
+		{"/area", internal.LinkKindNavigation},
+		{"/logo.png", internal.LinkKindImage},
+		{"/logo-1x.png", internal.LinkKindImage},
+		{"/logo-2x.png", internal.LinkKindImage},
+		{"/wide.png", internal.LinkKindImage},
+		{"/frame", internal.LinkKindFrame},
+		{"/clip.mp4", internal.LinkKindMedia},
+		{"/poster.png", internal.LinkKindImage},
+		{"/clip.webm", internal.LinkKindMedia},
+		{"/subs.vtt", internal.LinkKindMedia},
+		{"/sound.mp3", internal.LinkKindMedia},
+		{"/search", internal.LinkKindForm},
+		{"/logo.png", internal.LinkKindNavigation},
+	}
+
+	got, err := internal.HtmlTokenExtractor(strings.NewReader(html))
+	if err != nil {
+		t.Fatalf("HtmlTokenExtractor() error = %v", err)
+	}
+	gotLinks := make([]link, len(got.Links))
+	for i, l := range got.Links {
+		gotLinks[i] = link{URL: l.URL.String(), Kind: l.Kind}
+	}
+	if !reflect.DeepEqual(gotLinks, want) {
+		t.Errorf("HtmlTokenExtractor() links = %v, want %v", gotLinks, want)
+	}
+}
+
+func TestHtmlTokenExtractor_Attributes(t *testing.T) {
+	html := `<a href="/text" title=" About us " target="_blank" rel="noopener">
+	About <b>the</b>
+	team</a>
+<a href="/image"><img src="/logo.png" alt=" Logo "><img src="/other.png" alt="Other"> Home</a>
+<a href="/unclosed">first<a href="/next">second</a>
+<a href="/text">duplicate</a>`
+	want := []internal.Link{
+		{URL: &url.URL{Path: "/text"}, Rel: []string{"noopener"}, Text: "About the team", Title: "About us", Target: "_blank"},
+		{URL: &url.URL{Path: "/image"}, Text: "Home", Image: true, Alt: "Logo"},
+		{URL: &url.URL{Path: "/logo.png"}, Kind: internal.LinkKindImage},
+		{URL: &url.URL{Path: "/other.png"}, Kind: internal.LinkKindImage},
+		{URL: &url.URL{Path: "/unclosed"}, Text: "first"},
+		{URL: &url.URL{Path: "/next"}, Text: "second"},
+	}
+
+	got, err := internal.HtmlTokenExtractor(strings.NewReader(html))
+	if err != nil {
+		t.Fatalf("HtmlTokenExtractor() error = %v", err)
+	}
+	if !reflect.DeepEqual(got.Links, want) {
+		t.Errorf("HtmlTokenExtractor() links = %+v, want %+v", got.Links, want)
+	}
+}
@@ -42,19 +42,7 @@ func RemoveNonHTTPURLs(urls []*url.URL) []*url.URL {
 }
 
 // SameDomainFilter returns a URLFilterFunc that filters out URLs that do not have the same scheme and host
-// as the given target
-func SameDomainFilter(target *url.URL) URLFilterFunc {
-	return func(urls []*url.URL) []*url.URL {
-		filtered := make([]*url.URL, 0)
-		for _, u := range urls {
-			if u.Host != target.Host {
-				continue
-			}
-			if u.Scheme != target.Scheme {
-				continue
-			}
-			filtered = append(filtered, u)
-		}
-		return filtered
-	}
+// as one of the given targets
+func SameDomainFilter(targets ...*url.URL) URLFilterFunc {
+	return ScopeFilter(ScopeHost, false, targets...)
 }
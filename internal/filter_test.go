@@ -137,7 +137,7 @@ func TestRemoveNonHTTPURLs(t *testing.T) {
 
 func TestSameDomainFilter(t *testing.T) {
 	type params struct {
-		target *url.URL
+		targets []*url.URL
 	}
 	type args struct {
 		urls []*url.URL
@@ -150,7 +150,7 @@ func TestSameDomainFilter(t *testing.T) {
 	}{
 		{
 			name:   "removes different scheme",
-			params: params{target: &url.URL{Scheme: "https", Host: "test.com"}},
+			params: params{targets: []*url.URL{{Scheme: "https", Host: "test.com"}}},
 			args: args{urls: []*url.URL{
 				{Scheme: "https", Host: "test.com"},
 				{Scheme: "http", Host: "test.com"},
@@ -161,7 +161,7 @@ func TestSameDomainFilter(t *testing.T) {
 		},
 		{
 			name:   "includes different paths",
-			params: params{target: &url.URL{Scheme: "https", Host: "test.com"}},
+			params: params{targets: []*url.URL{{Scheme: "https", Host: "test.com"}}},
 			args: args{urls: []*url.URL{
 				{Scheme: "https", Host: "test.com"},
 				{Scheme: "https", Host: "test.com", Path: "contact"},
@@ -173,7 +173,7 @@ func TestSameDomainFilter(t *testing.T) {
 		},
 		{
 			name:   "removes different host",
-			params: params{target: &url.URL{Scheme: "https", Host: "test.com"}},
+			params: params{targets: []*url.URL{{Scheme: "https", Host: "test.com"}}},
 			args: args{urls: []*url.URL{
 				{Scheme: "https", Host: "test.com"},
 				{Scheme: "https", Host: "test.net"},
@@ -182,9 +182,25 @@ func TestSameDomainFilter(t *testing.T) {
 				{Scheme: "https", Host: "test.com"},
 			},
 		},
+		{
+			name: "includes any target",
+			params: params{targets: []*url.URL{
+				{Scheme: "https", Host: "test.com"},
+				{Scheme: "https", Host: "test.net"},
+			}},
+			args: args{urls: []*url.URL{
+				{Scheme: "https", Host: "test.com"},
+				{Scheme: "https", Host: "test.net"},
+				{Scheme: "https", Host: "test.org"},
+			}},
+			want: []*url.URL{
+				{Scheme: "https", Host: "test.com"},
+				{Scheme: "https", Host: "test.net"},
+			},
+		},
 		{
 			name:   "removes different subdomain",
-			params: params{target: &url.URL{Scheme: "https", Host: "test.com"}},
+			params: params{targets: []*url.URL{{Scheme: "https", Host: "test.com"}}},
 			args: args{urls: []*url.URL{
 				{Scheme: "https", Host: "test.com"},
 				{Scheme: "https", Host: "sub.test.com"},
@@ -196,7 +212,7 @@ func TestSameDomainFilter(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			f := internal.SameDomainFilter(tt.params.target)
+			f := internal.SameDomainFilter(tt.params.targets...)
 			if got := f(tt.args.urls); !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("SameDomainFilter() = %v, want %v", got, tt.want)
 			}
@@ -0,0 +1,298 @@
+package internal
+
+import (
+	"container/heap"
+	"context"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// DefaultMaxQueue is the max number of URLs that can wait to be crawled at once unless MaxQueue is set
+const DefaultMaxQueue = 100000
+
+// QueuedRequest describes a URL that is waiting to be crawled
+type QueuedRequest struct {
+	// URL is the URL that will be requested
+	URL *url.URL
+	// Origin is the URL of the page that the link was found on. It is nil for seeds.
+	Origin *url.URL
+	// Depth is the depth that the URL was discovered at. Seeds are at depth 1.
+	Depth int
+	// Kind is the kind of link that the URL was found in
+	Kind LinkKind
+	// HostQueued is the number of URLs on the same host that were queued before this one
+	HostQueued int
+}
+
+// PriorityFunc returns the priority of a queued URL. URLs with a lower priority are crawled first, and URLs with the
+// same priority are crawled in the order that they were queued. It is called once for each URL as it is queued, and
+// never concurrently.
+type PriorityFunc func(r QueuedRequest) float64
+
+// BreadthFirstPriority crawls URLs in order of depth, so that shallow pages are crawled before deep ones
+func BreadthFirstPriority(r QueuedRequest) float64 {
+	return float64(r.Depth)
+}
+
+// HostRoundRobinPriority takes turns between hosts, so that one large host doesn't hold up the rest of the crawl
+func HostRoundRobinPriority(r QueuedRequest) float64 {
+	return float64(r.HostQueued)
+}
+
+// QueueStats describes how the queue of URLs waiting to be crawled was used
+type QueueStats struct {
+	// Queued is the number of URLs that were added to the queue
+	Queued int
+	// Dropped is the number of URLs that weren't added to the queue because it was full
+	Dropped int
+	// MaxLen is the most URLs that were waiting in the queue at once
+	MaxLen int
+}
+
+// frontierItem is a request waiting in the frontier
+type frontierItem struct {
+	req crawlRequest
+	// level is the depth of the request when crawling one depth at a time, and otherwise 0
+	level    int
+	priority float64
+	// seq is the order that the item was pushed in, so that items with the same priority are first in first out
+	seq uint64
+}
+
+// frontierHeap is a min heap of frontierItems, implementing heap.Interface
+type frontierHeap []frontierItem
+
+// Len returns the number of items in the heap
+func (h frontierHeap) Len() int {
+	return len(h)
+}
+
+// Less orders items by level, then by priority, and then by the order they were pushed in
+func (h frontierHeap) Less(i, j int) bool {
+	if h[i].level != h[j].level {
+		return h[i].level < h[j].level
+	}
+	if h[i].priority != h[j].priority {
+		return h[i].priority < h[j].priority
+	}
+	return h[i].seq < h[j].seq
+}
+
+// Swap swaps the items at i and j
+func (h frontierHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+}
+
+// Push adds an item to the end of the heap
+func (h *frontierHeap) Push(x interface{}) {
+	*h = append(*h, x.(frontierItem))
+}
+
+// Pop removes the item from the end of the heap
+func (h *frontierHeap) Pop() interface{} {
+	old := *h
+	item := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return item
+}
+
+// frontier holds the requests that are waiting to be crawled, and hands them out in order of priority
+type frontier struct {
+	// priority orders the requests
+	priority PriorityFunc
+	// maxLen is the max number of requests that can wait at once, including after the frontier is closed
+	maxLen int
+	// strictDepth only hands out requests once every request at a lower depth is done
+	strictDepth bool
+	items       frontierHeap
+	seq         uint64
+	// hostQueued is the number of requests that have been queued for each host
+	hostQueued map[string]int
+	// active is the number of requests at each depth that have been handed out, but aren't done
+	active map[int]int
+	// inflight are the requests that have been handed out, but aren't done, by target URL
+	inflight map[string]crawlRequest
+	// abandoned are requests that were handed out, but dropped before they were crawled
+	abandoned []crawlRequest
+	stats     QueueStats
+	// closed is true once the frontier has stopped handing out requests. Requests are still kept for checkpoints.
+	closed bool
+	// ready is signalled when requests are pushed or done, so that dispatch can wait for them
+	ready chan struct{}
+	// wg is incremented for every request that is queued, and decremented for every request that is done or dropped
+	wg *sync.WaitGroup
+	// mu is an internal mutex to ensure routine safe access of the fields above
+	mu *sync.Mutex
+}
+
+// newFrontier returns an empty frontier for the given options. The priority defaults to BreadthFirstPriority.
+func newFrontier(o CrawlOptions, wg *sync.WaitGroup) *frontier {
+	priority := o.Priority
+	if priority == nil {
+		priority = BreadthFirstPriority
+	}
+	return &frontier{
+		priority:    priority,
+		maxLen:      o.maxQueue(),
+		strictDepth: o.StrictDepth,
+		hostQueued:  make(map[string]int),
+		active:      make(map[int]int),
+		inflight:    make(map[string]crawlRequest),
+		ready:       make(chan struct{}, 1),
+		wg:          wg,
+		mu:          &sync.Mutex{},
+	}
+}
+
+// push queues the requests, returning any that were dropped because the frontier is full. Requests that are pushed
+// after the frontier is closed are kept for checkpoints, up to the same limit, but never handed out.
+func (f *frontier) push(reqs ...crawlRequest) []crawlRequest {
+	f.mu.Lock()
+	dropped := make([]crawlRequest, 0)
+	for _, r := range reqs {
+		if len(f.items) >= f.maxLen {
+			f.stats.Dropped++
+			dropped = append(dropped, r)
+			continue
+		}
+		if f.closed {
+			heap.Push(&f.items, frontierItem{req: r, seq: f.seq})
+			f.seq++
+			continue
+		}
+		host := strings.ToLower(r.target.Host)
+		priority := f.priority(QueuedRequest{
+			URL:        r.target,
+			Origin:     r.origin,
+			Depth:      r.depth,
+			Kind:       r.kind,
+			HostQueued: f.hostQueued[host],
+		})
+		f.hostQueued[host]++
+		level := 0
+		if f.strictDepth {
+			level = r.depth
+		}
+		f.wg.Add(1)
+		heap.Push(&f.items, frontierItem{req: r, level: level, priority: priority, seq: f.seq})
+		f.seq++
+		f.stats.Queued++
+		if len(f.items) > f.stats.MaxLen {
+			f.stats.MaxLen = len(f.items)
+		}
+	}
+	f.mu.Unlock()
+
+	f.signal()
+	return dropped
+}
+
+// signal wakes up dispatch without blocking, as it only needs to know that something has changed
+func (f *frontier) signal() {
+	select {
+	case f.ready <- struct{}{}:
+	default:
+	}
+}
+
+// pop removes the request with the lowest priority and marks it as active, returning false if there are none that
+// can be handed out yet
+func (f *frontier) pop() (crawlRequest, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.items) == 0 {
+		return crawlRequest{}, false
+	}
+	if f.strictDepth {
+		for depth, n := range f.active {
+			if n > 0 && depth < f.items[0].req.depth {
+				return crawlRequest{}, false
+			}
+		}
+	}
+	r := heap.Pop(&f.items).(frontierItem).req
+	f.active[r.depth]++
+	f.inflight[r.target.String()] = r
+	return r, true
+}
+
+// done marks a request that was handed out by dispatch as finished, after any requests for the links on the page
+// have been pushed
+func (f *frontier) done(r crawlRequest) {
+	f.mu.Lock()
+	f.active[r.depth]--
+	delete(f.inflight, r.target.String())
+	f.mu.Unlock()
+	f.wg.Done()
+	f.signal()
+}
+
+// abandon marks a request that was handed out by dispatch as finished without being crawled. It is kept for
+// checkpoints, so that it can be crawled when the crawl is resumed.
+func (f *frontier) abandon(r crawlRequest) {
+	f.mu.Lock()
+	f.abandoned = append(f.abandoned, r)
+	f.mu.Unlock()
+	f.done(r)
+}
+
+// pending returns every request that hasn't been crawled, whether it is queued, in flight or abandoned
+func (f *frontier) pending() []crawlRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	reqs := make([]crawlRequest, 0, len(f.items)+len(f.inflight)+len(f.abandoned))
+	for _, item := range f.items {
+		reqs = append(reqs, item.req)
+	}
+	for _, r := range f.inflight {
+		reqs = append(reqs, r)
+	}
+	return append(reqs, f.abandoned...)
+}
+
+// len returns the number of requests waiting in the frontier
+func (f *frontier) len() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.items)
+}
+
+// queueStats returns the QueueStats for the frontier so far
+func (f *frontier) queueStats() QueueStats {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.stats
+}
+
+// dispatch sends requests to reqCh in order of priority until ctx is done, and then drops any that are left
+func (f *frontier) dispatch(ctx context.Context, reqCh chan<- crawlRequest) {
+	defer f.close()
+	for {
+		r, ok := f.pop()
+		if !ok {
+			select {
+			case <-f.ready:
+				continue
+			case <-ctx.Done():
+				return
+			}
+		}
+		select {
+		case reqCh <- r:
+		case <-ctx.Done():
+			f.abandon(r)
+			return
+		}
+	}
+}
+
+// close stops handing out requests, so that the crawl can finish without the requests that are still queued
+func (f *frontier) close() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+	for range f.items {
+		f.wg.Done()
+	}
+}
@@ -0,0 +1,190 @@
+package internal_test
+
+import (
+	"context"
+	"github.com/jmwri/web-crawler/internal"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCrawl_Priority(t *testing.T) {
+	pages := map[string]string{
+		"a/index.html": `<a href="/1">1</a><a href="/2">2</a><a href="https://b/1">b1</a><a href="/deep">d</a><a href="https://b/2">b2</a>`,
+		"a/deep":       `<a href="/3">3</a>`,
+	}
+	tests := []struct {
+		name     string
+		priority internal.PriorityFunc
+		want     []string
+	}{
+		{
+			name:     "breadth first",
+			priority: internal.BreadthFirstPriority,
+			want:     []string{"https://a/index.html", "https://a/1", "https://a/2", "https://b/1", "https://a/deep", "https://b/2", "https://a/3"},
+		},
+		{
+			name:     "host round robin",
+			priority: internal.HostRoundRobinPriority,
+			want:     []string{"https://a/index.html", "https://b/1", "https://a/1", "https://b/2", "https://a/2", "https://a/deep", "https://a/3"},
+		},
+		{
+			name: "custom",
+			priority: func(r internal.QueuedRequest) float64 {
+				if r.URL.Host == "b" {
+					return 0
+				}
+				return 1
+			},
+			want: []string{"https://a/index.html", "https://b/1", "https://b/2", "https://a/1", "https://a/2", "https://a/deep", "https://a/3"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make([]string, 0)
+			o := internal.NewCrawlOptions(&url.URL{Scheme: "https", Host: "a", Path: "index.html"}, false, 0, 1)
+			o.Priority = tt.priority
+			o.OnPage = func(e internal.PageEvent) {
+				got = append(got, e.URL.String())
+			}
+
+			_, err := internal.Crawl(testStringLoader(pages), internal.HtmlTokenExtractor, o)
+			if err != nil {
+				t.Fatalf("Crawl() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Crawl() crawled %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCrawl_MaxQueue(t *testing.T) {
+	tests := []struct {
+		name       string
+		pages      map[string]string
+		maxQueue   int
+		wantPages  int
+		wantStats  internal.QueueStats
+		wantReason internal.TruncateReason
+	}{
+		{
+			name: "default max",
+			pages: map[string]string{
+				"/index.html": `<a href="/1">1</a><a href="/2">2</a><a href="/3">3</a>`,
+			},
+			wantPages:  4,
+			wantStats:  internal.QueueStats{Queued: 4, MaxLen: 3},
+			wantReason: internal.NotTruncated,
+		},
+		{
+			name: "dropped",
+			pages: map[string]string{
+				"/index.html": `<a href="/1">1</a><a href="/2">2</a><a href="/3">3</a>`,
+			},
+			maxQueue:   1,
+			wantPages:  2,
+			wantStats:  internal.QueueStats{Queued: 2, Dropped: 2, MaxLen: 1},
+			wantReason: internal.TruncatedMaxQueue,
+		},
+		{
+			name: "dropped and found again",
+			pages: map[string]string{
+				"/index.html": `<a href="/1">1</a><a href="/2">2</a>`,
+				"/1":          `<a href="/2">2</a>`,
+			},
+			maxQueue:   1,
+			wantPages:  3,
+			wantStats:  internal.QueueStats{Queued: 3, Dropped: 1, MaxLen: 1},
+			wantReason: internal.NotTruncated,
+		},
+		{
+			// Only as many dropped URLs as MaxQueue are kept track of, so /3 still counts as dropped
+			name: "dropped beyond tracking and found again",
+			pages: map[string]string{
+				"/index.html": `<a href="/1">1</a><a href="/2">2</a><a href="/3">3</a>`,
+				"/1":          `<a href="/3">3</a>`,
+				"/3":          `<a href="/2">2</a>`,
+			},
+			maxQueue:   1,
+			wantPages:  4,
+			wantStats:  internal.QueueStats{Queued: 4, Dropped: 2, MaxLen: 1},
+			wantReason: internal.TruncatedMaxQueue,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := internal.NewCrawlOptions(&url.URL{Scheme: "https", Host: "localhost", Path: "index.html"}, true, 0, 1)
+			o.MaxQueue = tt.maxQueue
+
+			got, err := internal.Crawl(testStringLoader(tt.pages), internal.HtmlTokenExtractor, o)
+			if err != nil {
+				t.Fatalf("Crawl() error = %v", err)
+			}
+			if len(got.Pages()) != tt.wantPages {
+				t.Errorf("Crawl().Pages() got %d pages, want %d: %v", len(got.Pages()), tt.wantPages, got.URLs())
+			}
+			if got.QueueStats() != tt.wantStats {
+				t.Errorf("Crawl().QueueStats() = %+v, want %+v", got.QueueStats(), tt.wantStats)
+			}
+			if got.TruncateReason() != tt.wantReason {
+				t.Errorf("Crawl().TruncateReason() = %s, want %s", got.TruncateReason(), tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestCrawl_StrictDepth(t *testing.T) {
+	pages := map[string]string{
+		"/index.html": `<a href="/slow">s</a><a href="/fast">f</a>`,
+		"/slow":       `<a href="/x">x</a>`,
+		"/fast":       `<a href="/long">l</a>`,
+		"/long":       `<a href="/x">x</a>`,
+		"/x":          `<a href="/y">y</a>`,
+		"/y":          ``,
+	}
+	// /slow doesn't finish loading until /long has been queued from /fast. Without StrictDepth, /long could then be
+	// crawled before /slow, reaching /x at depth 4.
+	longQueued := make(chan struct{})
+	stringLoader := testStringLoader(pages)
+	var loader internal.LoaderFunc = func(ctx context.Context, p string) (*internal.Response, error) {
+		if strings.HasSuffix(p, "/slow") {
+			select {
+			case <-longQueued:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+		return stringLoader(ctx, p)
+	}
+	o := internal.NewCrawlOptions(&url.URL{Scheme: "https", Host: "localhost", Path: "index.html"}, true, 4, 2)
+	o.StrictDepth = true
+	// The priority is worked out as each URL is queued, and never concurrently
+	o.Priority = func(r internal.QueuedRequest) float64 {
+		if r.URL.Path == "/long" {
+			close(longQueued)
+		}
+		return internal.BreadthFirstPriority(r)
+	}
+
+	got, err := internal.Crawl(loader, internal.HtmlTokenExtractor, o)
+	if err != nil {
+		t.Fatalf("Crawl() error = %v", err)
+	}
+	wantDepths := map[string]int{
+		"https://localhost/index.html": 1,
+		"https://localhost/slow":       2,
+		"https://localhost/fast":       2,
+		"https://localhost/long":       3,
+		"https://localhost/x":          3,
+		"https://localhost/y":          4,
+	}
+	gotDepths := make(map[string]int)
+	for u, p := range got.Pages() {
+		gotDepths[u] = p.Depth
+	}
+	if !reflect.DeepEqual(gotDepths, wantDepths) {
+		t.Errorf("Crawl() depths = %v, want %v", gotDepths, wantDepths)
+	}
+}
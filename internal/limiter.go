@@ -0,0 +1,149 @@
+package internal
+
+import (
+	"context"
+	"io"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// NewHostLimiter returns a new HostLimiter.
+// Requests to each host start at most rps times per second, and at least delay apart. Set either to 0 for no limit.
+// maxConcurrent limits the number of requests to each host at once. Set to 0 for no limit.
+func NewHostLimiter(rps float64, delay time.Duration, maxConcurrent int) *HostLimiter {
+	interval := delay
+	if rps > 0 {
+		if perRequest := time.Duration(float64(time.Second) / rps); perRequest > interval {
+			interval = perRequest
+		}
+	}
+	if maxConcurrent < 0 {
+		maxConcurrent = 0
+	}
+	return &HostLimiter{
+		interval:      interval,
+		maxConcurrent: maxConcurrent,
+		hosts:         make(map[string]*hostState),
+		mu:            &sync.Mutex{},
+	}
+}
+
+// HostLimiter limits how often, and how many at once, requests are made to each host
+type HostLimiter struct {
+	// interval is the min time between the start of requests to the same host
+	interval time.Duration
+	// maxConcurrent is the max number of requests to the same host at once. 0 means no limit.
+	maxConcurrent int
+	// hosts contains the state of each host that has been requested
+	hosts map[string]*hostState
+	// mu is an internal mutex to ensure routine safe access of hosts
+	mu *sync.Mutex
+}
+
+// hostState tracks requests to a single host
+type hostState struct {
+	// next is the earliest time that the next request to the host can start
+	next time.Time
+	// interval overrides the HostLimiter interval for this host if it is longer
+	interval time.Duration
+	// slots has a value for each request that is in progress. It is nil if there is no concurrency limit.
+	slots chan struct{}
+}
+
+// host returns the state for the given host, creating it if necessary
+func (l *HostLimiter) host(host string) *hostState {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	h, ok := l.hosts[host]
+	if !ok {
+		h = &hostState{}
+		if l.maxConcurrent > 0 {
+			h.slots = make(chan struct{}, l.maxConcurrent)
+		}
+		l.hosts[host] = h
+	}
+	return h
+}
+
+// reserve returns how long to wait until a request to the host can start, and reserves that start time
+func (l *HostLimiter) reserve(h *hostState) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	start := h.next
+	if start.Before(now) {
+		start = now
+	}
+	interval := l.interval
+	if h.interval > interval {
+		interval = h.interval
+	}
+	h.next = start.Add(interval)
+	return start.Sub(now)
+}
+
+// SetHostInterval sets the min time between the start of requests to the given host.
+// It only has an effect if it is longer than the interval that the HostLimiter was created with.
+func (l *HostLimiter) SetHostInterval(host string, interval time.Duration) {
+	h := l.host(host)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	h.interval = interval
+}
+
+// Acquire blocks until a request to the given host can start, or ctx is done.
+// The returned func must be called once the request is complete.
+func (l *HostLimiter) Acquire(ctx context.Context, host string) (func(), error) {
+	h := l.host(host)
+	release := func() {}
+	if h.slots != nil {
+		select {
+		case h.slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		release = func() { <-h.slots }
+	}
+	if err := sleep(ctx, l.reserve(h)); err != nil {
+		release()
+		return nil, err
+	}
+	return release, nil
+}
+
+// LoaderWithHostLimiter wraps a LoaderFunc so that each request waits for the HostLimiter.
+// The request is treated as complete once the response body is closed.
+func LoaderWithHostLimiter(l LoaderFunc, limiter *HostLimiter) LoaderFunc {
+	return func(ctx context.Context, p string) (*Response, error) {
+		u, err := url.Parse(p)
+		if err != nil {
+			return l(ctx, p)
+		}
+		release, err := limiter.Acquire(ctx, u.Host)
+		if err != nil {
+			return nil, err
+		}
+		res, err := l(ctx, p)
+		if err != nil || res == nil || res.Body == nil {
+			// The body isn't read when there is an error, so the request is already complete
+			release()
+			return res, err
+		}
+		res.Body = &releasingReadCloser{ReadCloser: res.Body, release: release, once: &sync.Once{}}
+		return res, err
+	}
+}
+
+// releasingReadCloser calls release when it is first closed
+type releasingReadCloser struct {
+	io.ReadCloser
+	release func()
+	once    *sync.Once
+}
+
+// Close the underlying io.ReadCloser and release the request
+func (r *releasingReadCloser) Close() error {
+	r.once.Do(r.release)
+	return r.ReadCloser.Close()
+}
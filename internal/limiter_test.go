@@ -0,0 +1,113 @@
+package internal_test
+
+import (
+	"context"
+	"errors"
+	"github.com/jmwri/web-crawler/internal"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewHostLimiter_Interval(t *testing.T) {
+	tests := []struct {
+		name         string
+		rps          float64
+		delay        time.Duration
+		wantInterval time.Duration
+	}{
+		{name: "rate", rps: 20, wantInterval: time.Millisecond * 50},
+		{name: "delay", delay: time.Millisecond * 50, wantInterval: time.Millisecond * 50},
+		{name: "delay slower than rate", rps: 100, delay: time.Millisecond * 50, wantInterval: time.Millisecond * 50},
+		{name: "rate slower than delay", rps: 20, delay: time.Millisecond * 10, wantInterval: time.Millisecond * 50},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := internal.NewHostLimiter(tt.rps, tt.delay, 0)
+			ctx := context.Background()
+
+			timeBefore := time.Now()
+			for i := 0; i < 3; i++ {
+				release, err := l.Acquire(ctx, "test.com")
+				if err != nil {
+					t.Fatalf("Acquire() error = %v", err)
+				}
+				release()
+			}
+			duration := time.Since(timeBefore)
+
+			// The first request starts straight away, so we only wait for 2 intervals
+			expectedDuration := tt.wantInterval * 2
+			// Allow an extra 50% of time
+			upperBound := expectedDuration + (expectedDuration / 2)
+			if duration < expectedDuration || duration > upperBound {
+				t.Errorf("expecting to wait from %s to %s, waited for %s", expectedDuration, upperBound, duration)
+			}
+		})
+	}
+}
+
+func TestHostLimiter_Acquire_PerHost(t *testing.T) {
+	l := internal.NewHostLimiter(0, time.Hour, 0)
+	ctx := context.Background()
+
+	for _, host := range []string{"test.com", "test.net", "sub.test.com"} {
+		timeBefore := time.Now()
+		release, err := l.Acquire(ctx, host)
+		if err != nil {
+			t.Fatalf("Acquire(%s) error = %v", host, err)
+		}
+		release()
+		if duration := time.Since(timeBefore); duration > time.Millisecond*100 {
+			t.Errorf("expecting first request to %s to start straight away, waited for %s", host, duration)
+		}
+	}
+}
+
+func TestHostLimiter_Acquire_MaxConcurrent(t *testing.T) {
+	l := internal.NewHostLimiter(0, 0, 1)
+
+	release, err := l.Acquire(context.Background(), "test.com")
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	if _, err := l.Acquire(ctx, "test.com"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Acquire() while full error = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	release()
+	release, err = l.Acquire(context.Background(), "test.com")
+	if err != nil {
+		t.Fatalf("Acquire() after release error = %v", err)
+	}
+	release()
+}
+
+func TestLoaderWithHostLimiter_ReleasesOnClose(t *testing.T) {
+	var loader internal.LoaderFunc = func(ctx context.Context, p string) (*internal.Response, error) {
+		return &internal.Response{Body: ioutil.NopCloser(strings.NewReader("page"))}, nil
+	}
+	limited := internal.LoaderWithHostLimiter(loader, internal.NewHostLimiter(0, 0, 1))
+
+	res, err := limited(context.Background(), "https://test.com/page")
+	if err != nil {
+		t.Fatalf("loader error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	if _, err := limited(ctx, "https://test.com/other"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("loader while body open error = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	res.Body.Close()
+	res, err = limited(context.Background(), "https://test.com/other")
+	if err != nil {
+		t.Fatalf("loader after close error = %v", err)
+	}
+	res.Body.Close()
+}
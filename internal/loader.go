@@ -1,55 +1,344 @@
 package internal
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"io"
+	"mime"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 )
 
 // LoaderFunc loads requested page
-type LoaderFunc func(p string) (io.ReadCloser, error)
+type LoaderFunc func(ctx context.Context, p string) (*Response, error)
+
+// Response is a page loaded by a LoaderFunc
+type Response struct {
+	// Body is the content of the page, which must be closed by the caller
+	Body io.ReadCloser
+	// StatusCode is the HTTP status code of the response. It is 0 if the page wasn't loaded over HTTP.
+	StatusCode int
+	// Header contains the response headers, if any
+	Header http.Header
+	// URL is the final URL of the page after any redirects. It may be nil if the page wasn't redirected.
+	URL *url.URL
+	// Redirects are the hops that were followed to reach URL, in order
+	Redirects []Redirect
+}
+
+// Redirect is a single hop in a redirect chain
+type Redirect struct {
+	// URL is the URL that responded with a redirect
+	URL string
+	// StatusCode is the HTTP status code of the redirect
+	StatusCode int
+}
+
+// DefaultContentTypes are the content types that HTTPGetLoader allows unless WithContentTypes is given
+var DefaultContentTypes = []string{"text/html", "application/xhtml+xml"}
 
 // NewHTTPGetLoader returns a new HTTPGetLoader
-func NewHTTPGetLoader(client *http.Client) HTTPGetLoader {
-	return HTTPGetLoader{
-		client: client,
+func NewHTTPGetLoader(client *http.Client, opts ...HTTPGetLoaderOption) HTTPGetLoader {
+	l := HTTPGetLoader{
+		client:       client,
+		contentTypes: DefaultContentTypes,
+	}
+	for _, opt := range opts {
+		opt(&l)
+	}
+	return l
+}
+
+// HTTPGetLoaderOption configures an HTTPGetLoader
+type HTTPGetLoaderOption func(l *HTTPGetLoader)
+
+// WithUserAgent sets the User-Agent header sent with each request
+func WithUserAgent(userAgent string) HTTPGetLoaderOption {
+	return func(l *HTTPGetLoader) {
+		l.userAgent = userAgent
+	}
+}
+
+// WithContentTypes sets the media types that are loaded, ie "text/html". Responses of any other type are
+// returned with a ContentTypeError. If no types are given then every type is loaded.
+func WithContentTypes(types ...string) HTTPGetLoaderOption {
+	return func(l *HTTPGetLoader) {
+		l.contentTypes = types
+	}
+}
+
+// WithHeadProbe makes a HEAD request before each GET request, so that responses with a content type that isn't
+// allowed are never downloaded
+func WithHeadProbe() HTTPGetLoaderOption {
+	return func(l *HTTPGetLoader) {
+		l.headProbe = true
 	}
 }
 
 // HTTPGetLoader loads pages with an HTTP GET request
 type HTTPGetLoader struct {
 	client *http.Client
+	// userAgent is sent as the User-Agent header if it isn't empty
+	userAgent string
+	// contentTypes are the media types that are allowed to be loaded. Empty allows everything.
+	contentTypes []string
+	// headProbe checks the content type with a HEAD request before loading the page
+	headProbe bool
+}
+
+// Load the requested page with an HTTP GET request. The request is aborted if ctx is done.
+func (l *HTTPGetLoader) Load(ctx context.Context, p string) (*Response, error) {
+	if l.headProbe && len(l.contentTypes) > 0 {
+		// Servers don't always handle HEAD requests properly, so any failure falls back to the GET request
+		probed, err := l.do(ctx, http.MethodHead, p)
+		if err == nil {
+			probed.Body.Close()
+			probed.Body = http.NoBody
+			if err := l.checkContentType(probed); err != nil {
+				return probed, err
+			}
+		}
+	}
+
+	loaded, err := l.do(ctx, http.MethodGet, p)
+	if err != nil {
+		return loaded, err
+	}
+	if err := l.checkContentType(loaded); err != nil {
+		// The body isn't wanted, so close it straight away rather than leaving it to the caller
+		loaded.Body.Close()
+		loaded.Body = http.NoBody
+		return loaded, err
+	}
+	return loaded, nil
 }
 
-// Load the requested page with an HTTP GET request
-func (l *HTTPGetLoader) Load(p string) (io.ReadCloser, error) {
-	res, err := l.client.Get(p)
+// do makes a request with the given method, and returns a StatusError if the response isn't successful
+func (l *HTTPGetLoader) do(ctx context.Context, method string, p string) (*Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, p, nil)
+	if err != nil {
+		return nil, err
+	}
+	if l.userAgent != "" {
+		req.Header.Set("User-Agent", l.userAgent)
+	}
+
+	// Copy the client so that we can record each redirect that it follows for this request
+	var redirects []Redirect
+	client := *l.client
+	client.CheckRedirect = func(next *http.Request, via []*http.Request) error {
+		var err error
+		if l.client.CheckRedirect != nil {
+			err = l.client.CheckRedirect(next, via)
+		} else if len(via) >= 10 {
+			// Same as the default policy of http.Client
+			err = errors.New("stopped after 10 redirects")
+		}
+		if err == nil {
+			redirects = append(redirects, Redirect{
+				URL:        via[len(via)-1].URL.String(),
+				StatusCode: next.Response.StatusCode,
+			})
+		}
+		return err
+	}
+
+	res, err := client.Do(req)
 	if err != nil {
 		return nil, err
 	}
-	// TODO: Check content type
+	loaded := &Response{
+		Body:       res.Body,
+		StatusCode: res.StatusCode,
+		Header:     res.Header,
+		URL:        res.Request.URL,
+		Redirects:  redirects,
+	}
 	if res.StatusCode < 200 || res.StatusCode > 299 {
-		return res.Body, errors.New("failed to load page")
+		// The body of an unsuccessful response isn't wanted, so close it rather than leaving it to the caller
+		res.Body.Close()
+		loaded.Body = http.NoBody
+		return loaded, &StatusError{
+			StatusCode: res.StatusCode,
+			RetryAfter: parseRetryAfter(res.Header.Get("Retry-After"), time.Now()),
+		}
+	}
+	return loaded, nil
+}
+
+// parseRetryAfter parses a Retry-After header, which is either a number of seconds or an HTTP date.
+// It returns 0 if the header is empty, invalid, or in the past.
+func parseRetryAfter(header string, now time.Time) time.Duration {
+	if header == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(header); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(header); err == nil && date.After(now) {
+		return date.Sub(now)
+	}
+	return 0
+}
+
+// checkContentType returns a ContentTypeError if the response has a content type that isn't allowed.
+// Responses without a content type are allowed.
+func (l *HTTPGetLoader) checkContentType(res *Response) error {
+	header := res.Header.Get("Content-Type")
+	if len(l.contentTypes) == 0 || header == "" {
+		return nil
 	}
-	return res.Body, err
+	mediaType, _, err := mime.ParseMediaType(header)
+	if err != nil {
+		return &ContentTypeError{ContentType: header}
+	}
+	for _, t := range l.contentTypes {
+		if strings.EqualFold(mediaType, t) {
+			return nil
+		}
+	}
+	return &ContentTypeError{ContentType: mediaType}
+}
+
+// ContentTypeError is returned when a page has a content type that isn't allowed
+type ContentTypeError struct {
+	ContentType string
+}
+
+// Error describes the content type
+func (e *ContentTypeError) Error() string {
+	return fmt.Sprintf("non-HTML resource: %s", e.ContentType)
+}
+
+// StatusError is returned when a page responds with an unsuccessful HTTP status
+type StatusError struct {
+	StatusCode int
+	// RetryAfter is how long the server asked us to wait before trying again, from the Retry-After header.
+	// It is 0 if the header wasn't sent.
+	RetryAfter time.Duration
+}
+
+// Error describes the HTTP status
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("failed to load page: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// AttemptsError wraps the error from the final attempt made by LoaderWithRetry
+type AttemptsError struct {
+	// Attempts is the number of attempts that were made
+	Attempts int
+	// Err is the error from the final attempt
+	Err error
+}
+
+// Error describes the final error
+func (e *AttemptsError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the error from the final attempt
+func (e *AttemptsError) Unwrap() error {
+	return e.Err
 }
 
-// LoaderWithRetry wraps a LoaderFunc with a retry
+// LoaderWithRetry wraps a LoaderFunc with a retry, using the default RetryPolicy rules for which errors to retry.
+// Retries stop as soon as ctx is done.
 func LoaderWithRetry(l LoaderFunc, b BackoffFunc, attempts int) LoaderFunc {
-	return func(p string) (io.ReadCloser, error) {
+	return LoaderWithRetryPolicy(l, RetryPolicy{
+		MaxAttempts: attempts,
+		Backoff:     b,
+	})
+}
+
+// RetryPolicy decides whether, and when, a failed load is retried
+type RetryPolicy struct {
+	// MaxAttempts is the max number of attempts, including the first. Must be at least 1.
+	MaxAttempts int
+	// Backoff returns the duration to sleep before making an attempt
+	Backoff BackoffFunc
+	// MaxRetryAfter caps how long a Retry-After header can make us wait before the next attempt.
+	// Set to 0 for no cap.
+	MaxRetryAfter time.Duration
+	// Retryable returns whether a failed attempt should be retried. Defaults to RetryableError if nil.
+	Retryable func(err error) bool
+}
+
+// RetryableError returns true for errors that may succeed if retried: network errors, 5xx, 408 and 429 responses.
+// Other 4xx responses, non-HTML resources, and cancelled requests are final.
+func RetryableError(err error) bool {
+	var statusErr *StatusError
+	var contentTypeErr *ContentTypeError
+	switch {
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return false
+	case errors.As(err, &statusErr):
+		code := statusErr.StatusCode
+		return code >= 500 || code == http.StatusRequestTimeout || code == http.StatusTooManyRequests
+	case errors.As(err, &contentTypeErr):
+		return false
+	default:
+		return true
+	}
+}
+
+// LoaderWithRetryPolicy wraps a LoaderFunc with a retry according to the RetryPolicy.
+// Retries stop as soon as ctx is done. The body of each failed attempt is closed.
+func LoaderWithRetryPolicy(l LoaderFunc, policy RetryPolicy) LoaderFunc {
+	retryable := policy.Retryable
+	if retryable == nil {
+		retryable = RetryableError
+	}
+	return func(ctx context.Context, p string) (*Response, error) {
 		var attempt int
+		wait := policy.Backoff(1)
 		for {
 			attempt += 1
-			time.Sleep(b(attempt))
-			res, err := l(p)
-			if err != nil {
-				if attempt >= attempts {
-					return nil, err
+			if err := sleep(ctx, wait); err != nil {
+				return nil, &AttemptsError{Attempts: attempt - 1, Err: err}
+			}
+			res, err := l(ctx, p)
+			if err == nil {
+				return res, nil
+			}
+			// The body isn't read when there is an error, so it must be closed to avoid leaking the connection
+			if res != nil && res.Body != nil {
+				res.Body.Close()
+				res.Body = http.NoBody
+			}
+			if attempt >= policy.MaxAttempts || ctx.Err() != nil || !retryable(err) {
+				return res, &AttemptsError{Attempts: attempt, Err: err}
+			}
+
+			wait = policy.Backoff(attempt + 1)
+			var statusErr *StatusError
+			if errors.As(err, &statusErr) && statusErr.RetryAfter > wait {
+				wait = statusErr.RetryAfter
+				if policy.MaxRetryAfter > 0 && wait > policy.MaxRetryAfter {
+					wait = policy.MaxRetryAfter
 				}
-				continue
 			}
-			return res, err
 		}
 	}
 }
+
+// sleep for the given duration, returning early with the context error if ctx is done first
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -9,6 +9,7 @@ import (
 func TestNewHTTPGetLoader(t *testing.T) {
 	type args struct {
 		client *http.Client
+		opts   []HTTPGetLoaderOption
 	}
 	tests := []struct {
 		name string
@@ -18,12 +19,22 @@ func TestNewHTTPGetLoader(t *testing.T) {
 		{
 			name: "creates successfully",
 			args: args{client: http.DefaultClient},
-			want: HTTPGetLoader{http.DefaultClient},
+			want: HTTPGetLoader{client: http.DefaultClient, contentTypes: DefaultContentTypes},
+		},
+		{
+			name: "with user agent",
+			args: args{client: http.DefaultClient, opts: []HTTPGetLoaderOption{WithUserAgent("test-agent")}},
+			want: HTTPGetLoader{client: http.DefaultClient, userAgent: "test-agent", contentTypes: DefaultContentTypes},
+		},
+		{
+			name: "with content types and head probe",
+			args: args{client: http.DefaultClient, opts: []HTTPGetLoaderOption{WithContentTypes("text/plain"), WithHeadProbe()}},
+			want: HTTPGetLoader{client: http.DefaultClient, contentTypes: []string{"text/plain"}, headProbe: true},
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := NewHTTPGetLoader(tt.args.client); !reflect.DeepEqual(got, tt.want) {
+			if got := NewHTTPGetLoader(tt.args.client, tt.args.opts...); !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("NewHTTPGetLoader() = %v, want %v", got, tt.want)
 			}
 		})
@@ -1,6 +1,7 @@
 package internal_test
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/jmwri/web-crawler/internal"
@@ -10,6 +11,7 @@ import (
 	"net/http/httptest"
 	"os"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -80,12 +82,16 @@ func TestHTTPGetLoader_Load(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			l := internal.NewHTTPGetLoader(tt.fields.client)
-			got, err := l.Load(tt.args.p)
+			got, err := l.Load(context.Background(), tt.args.p)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Load() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
 			if tt.wantErr {
+				var statusErr *internal.StatusError
+				if !errors.As(err, &statusErr) {
+					t.Errorf("Load() error = %v, want StatusError", err)
+				}
 				// Don't try to load any files if we expect an error
 				return
 			}
@@ -95,7 +101,7 @@ func TestHTTPGetLoader_Load(t *testing.T) {
 				t.Fatalf("failed to read wanted file %s: %s", tt.want, err)
 			}
 
-			gotContents, err := ioutil.ReadAll(got)
+			gotContents, err := ioutil.ReadAll(got.Body)
 			if err != nil {
 				t.Fatalf("failed to read all of file %s: %s", tt.args.p, err)
 			}
@@ -115,14 +121,14 @@ func TestHTTPGetLoader_Load(t *testing.T) {
 func TestLoaderWithRetry_RightNumberOfAttempts(t *testing.T) {
 	var loaderCalls int
 
-	countingLoader := func(p string) (io.ReadCloser, error) {
+	countingLoader := func(ctx context.Context, p string) (*internal.Response, error) {
 		loaderCalls++
 		return nil, errors.New("always fail")
 	}
 	noBackoff := func(attempt int) time.Duration { return 0 }
 
 	loader := internal.LoaderWithRetry(countingLoader, noBackoff, 5)
-	_, err := loader("test page")
+	_, err := loader(context.Background(), "test page")
 
 	if err == nil {
 		t.Error("expecting err")
@@ -130,10 +136,14 @@ func TestLoaderWithRetry_RightNumberOfAttempts(t *testing.T) {
 	if loaderCalls != 5 {
 		t.Errorf("expecting %d calls, got %d", 5, loaderCalls)
 	}
+	var attemptsErr *internal.AttemptsError
+	if !errors.As(err, &attemptsErr) || attemptsErr.Attempts != 5 {
+		t.Errorf("expecting AttemptsError with %d attempts, got %v", 5, err)
+	}
 }
 
 func TestLoaderWithRetry_SleepsCorrectly(t *testing.T) {
-	failingLoader := func(p string) (io.ReadCloser, error) {
+	failingLoader := func(ctx context.Context, p string) (*internal.Response, error) {
 		return nil, errors.New("always fail")
 	}
 	smallBackoff := func(attempt int) time.Duration { return (time.Millisecond * 100) * time.Duration(attempt) }
@@ -141,7 +151,7 @@ func TestLoaderWithRetry_SleepsCorrectly(t *testing.T) {
 	loader := internal.LoaderWithRetry(failingLoader, smallBackoff, 5)
 
 	timeBefore := time.Now()
-	_, err := loader("test page")
+	_, err := loader(context.Background(), "test page")
 	duration := time.Since(timeBefore)
 
 	if err == nil {
@@ -162,3 +172,269 @@ func TestLoaderWithRetry_SleepsCorrectly(t *testing.T) {
 		t.Errorf("expecting to sleep from %s to %s, slept for %s", expectedDuration, upperBound, duration)
 	}
 }
+
+func TestHTTPGetLoader_Load_Cancelled(t *testing.T) {
+	ts := httptest.NewServer(testSiteHandler)
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	l := internal.NewHTTPGetLoader(ts.Client())
+	_, err := l.Load(ctx, ts.URL+"/index.html")
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Load() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestLoaderWithRetry_StopsWhenCancelled(t *testing.T) {
+	var loaderCalls int
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cancellingLoader := func(ctx context.Context, p string) (*internal.Response, error) {
+		loaderCalls++
+		cancel()
+		return nil, errors.New("always fail")
+	}
+	longBackoff := func(attempt int) time.Duration { return time.Duration(attempt-1) * time.Hour }
+
+	loader := internal.LoaderWithRetry(cancellingLoader, longBackoff, 5)
+	_, err := loader(ctx, "test page")
+
+	if err == nil {
+		t.Error("expecting err")
+	}
+	if loaderCalls != 1 {
+		t.Errorf("expecting %d calls, got %d", 1, loaderCalls)
+	}
+}
+
+func TestHTTPGetLoader_Load_ContentType(t *testing.T) {
+	var gets int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			gets++
+		}
+		switch r.URL.Path {
+		case "/page":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		case "/xhtml":
+			w.Header().Set("Content-Type", "application/xhtml+xml")
+		case "/image.png":
+			w.Header().Set("Content-Type", "image/png")
+		case "/no-head.pdf":
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			w.Header().Set("Content-Type", "application/pdf")
+		}
+		w.Write([]byte("content"))
+	}))
+	defer ts.Close()
+
+	tests := []struct {
+		name     string
+		opts     []internal.HTTPGetLoaderOption
+		p        string
+		wantErr  bool
+		wantGets int
+	}{
+		{name: "allows html", p: "/page", wantGets: 1},
+		{name: "allows xhtml", p: "/xhtml", wantGets: 1},
+		{name: "rejects image", p: "/image.png", wantErr: true, wantGets: 1},
+		{name: "allows configured type", opts: []internal.HTTPGetLoaderOption{internal.WithContentTypes("image/png")}, p: "/image.png", wantGets: 1},
+		{name: "allows any type", opts: []internal.HTTPGetLoaderOption{internal.WithContentTypes()}, p: "/image.png", wantGets: 1},
+		{name: "probe allows html", opts: []internal.HTTPGetLoaderOption{internal.WithHeadProbe()}, p: "/page", wantGets: 1},
+		{name: "probe rejects image without get", opts: []internal.HTTPGetLoaderOption{internal.WithHeadProbe()}, p: "/image.png", wantErr: true, wantGets: 0},
+		{name: "probe falls back to get", opts: []internal.HTTPGetLoaderOption{internal.WithHeadProbe()}, p: "/no-head.pdf", wantErr: true, wantGets: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gets = 0
+			l := internal.NewHTTPGetLoader(ts.Client(), tt.opts...)
+			got, err := l.Load(context.Background(), ts.URL+tt.p)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var contentTypeErr *internal.ContentTypeError
+			if tt.wantErr && !errors.As(err, &contentTypeErr) {
+				t.Errorf("Load() error = %v, want ContentTypeError", err)
+			}
+			if got != nil {
+				got.Body.Close()
+			}
+			if gets != tt.wantGets {
+				t.Errorf("expecting %d GET requests, got %d", tt.wantGets, gets)
+			}
+		})
+	}
+}
+
+func TestLoaderWithRetry_DoesNotRetryContentType(t *testing.T) {
+	var loaderCalls int
+
+	countingLoader := func(ctx context.Context, p string) (*internal.Response, error) {
+		loaderCalls++
+		return nil, &internal.ContentTypeError{ContentType: "image/png"}
+	}
+	noBackoff := func(attempt int) time.Duration { return 0 }
+
+	loader := internal.LoaderWithRetry(countingLoader, noBackoff, 5)
+	_, err := loader(context.Background(), "test page")
+
+	if err == nil {
+		t.Error("expecting err")
+	}
+	if loaderCalls != 1 {
+		t.Errorf("expecting %d calls, got %d", 1, loaderCalls)
+	}
+}
+
+// closeTrackingBody records whether it has been closed
+type closeTrackingBody struct {
+	io.Reader
+	closed bool
+}
+
+// Close marks the body as closed
+func (b *closeTrackingBody) Close() error {
+	b.closed = true
+	return nil
+}
+
+func TestLoaderWithRetryPolicy_Retryable(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		wantCalls int
+	}{
+		{name: "network error", err: errors.New("connection reset"), wantCalls: 5},
+		{name: "500", err: &internal.StatusError{StatusCode: 500}, wantCalls: 5},
+		{name: "503", err: &internal.StatusError{StatusCode: 503}, wantCalls: 5},
+		{name: "408", err: &internal.StatusError{StatusCode: 408}, wantCalls: 5},
+		{name: "429", err: &internal.StatusError{StatusCode: 429}, wantCalls: 5},
+		{name: "404", err: &internal.StatusError{StatusCode: 404}, wantCalls: 1},
+		{name: "410", err: &internal.StatusError{StatusCode: 410}, wantCalls: 1},
+		{name: "403", err: &internal.StatusError{StatusCode: 403}, wantCalls: 1},
+		{name: "non-HTML", err: &internal.ContentTypeError{ContentType: "image/png"}, wantCalls: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var loaderCalls int
+			var bodies []*closeTrackingBody
+			failingLoader := func(ctx context.Context, p string) (*internal.Response, error) {
+				loaderCalls++
+				body := &closeTrackingBody{Reader: strings.NewReader("error page")}
+				bodies = append(bodies, body)
+				return &internal.Response{Body: body}, tt.err
+			}
+			noBackoff := func(attempt int) time.Duration { return 0 }
+
+			loader := internal.LoaderWithRetryPolicy(failingLoader, internal.RetryPolicy{MaxAttempts: 5, Backoff: noBackoff})
+			_, err := loader(context.Background(), "test page")
+
+			if !errors.Is(err, tt.err) {
+				t.Errorf("expecting err %v, got %v", tt.err, err)
+			}
+			if loaderCalls != tt.wantCalls {
+				t.Errorf("expecting %d calls, got %d", tt.wantCalls, loaderCalls)
+			}
+			for i, body := range bodies {
+				if !body.closed {
+					t.Errorf("expecting body of attempt %d to be closed", i+1)
+				}
+			}
+		})
+	}
+}
+
+func TestLoaderWithRetryPolicy_RetryAfter(t *testing.T) {
+	tests := []struct {
+		name          string
+		retryAfter    time.Duration
+		maxRetryAfter time.Duration
+		wantDuration  time.Duration
+	}{
+		{name: "honors retry after", retryAfter: time.Millisecond * 200, wantDuration: time.Millisecond * 200},
+		{name: "caps retry after", retryAfter: time.Hour, maxRetryAfter: time.Millisecond * 200, wantDuration: time.Millisecond * 200},
+		{name: "backoff longer than retry after", retryAfter: time.Millisecond, wantDuration: time.Millisecond * 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var loaderCalls int
+			rateLimitedLoader := func(ctx context.Context, p string) (*internal.Response, error) {
+				loaderCalls++
+				if loaderCalls == 1 {
+					return nil, &internal.StatusError{StatusCode: 429, RetryAfter: tt.retryAfter}
+				}
+				return &internal.Response{Body: ioutil.NopCloser(strings.NewReader("page"))}, nil
+			}
+			smallBackoff := func(attempt int) time.Duration { return (time.Millisecond * 100) * time.Duration(attempt-1) }
+
+			loader := internal.LoaderWithRetryPolicy(rateLimitedLoader, internal.RetryPolicy{
+				MaxAttempts:   2,
+				Backoff:       smallBackoff,
+				MaxRetryAfter: tt.maxRetryAfter,
+			})
+
+			timeBefore := time.Now()
+			_, err := loader(context.Background(), "test page")
+			duration := time.Since(timeBefore)
+
+			if err != nil {
+				t.Fatalf("unexpected err %v", err)
+			}
+			// Allow an extra 25% of time
+			upperBound := tt.wantDuration + (tt.wantDuration / 4)
+			if duration < tt.wantDuration || duration > upperBound {
+				t.Errorf("expecting to sleep from %s to %s, slept for %s", tt.wantDuration, upperBound, duration)
+			}
+		})
+	}
+}
+
+func TestHTTPGetLoader_Load_RetryAfter(t *testing.T) {
+	retryAt := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/seconds":
+			w.Header().Set("Retry-After", "120")
+		case "/date":
+			w.Header().Set("Retry-After", retryAt)
+		case "/invalid":
+			w.Header().Set("Retry-After", "soon")
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	tests := []struct {
+		p       string
+		wantMin time.Duration
+		wantMax time.Duration
+	}{
+		{p: "/seconds", wantMin: time.Minute * 2, wantMax: time.Minute * 2},
+		{p: "/date", wantMin: time.Minute * 59, wantMax: time.Hour},
+		{p: "/invalid", wantMin: 0, wantMax: 0},
+		{p: "/missing", wantMin: 0, wantMax: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.p, func(t *testing.T) {
+			l := internal.NewHTTPGetLoader(ts.Client())
+			got, err := l.Load(context.Background(), ts.URL+tt.p)
+			var statusErr *internal.StatusError
+			if !errors.As(err, &statusErr) {
+				t.Fatalf("Load() error = %v, want StatusError", err)
+			}
+			if statusErr.RetryAfter < tt.wantMin || statusErr.RetryAfter > tt.wantMax {
+				t.Errorf("RetryAfter = %s, want from %s to %s", statusErr.RetryAfter, tt.wantMin, tt.wantMax)
+			}
+			if got.Body != http.NoBody {
+				t.Errorf("expecting body of failed response to be closed and replaced with http.NoBody")
+			}
+		})
+	}
+}
@@ -2,6 +2,8 @@ package internal
 
 import (
 	"net/url"
+	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -38,3 +40,275 @@ func RemoveTrailingSlash(u *url.URL) {
 func RemoveFragment(u *url.URL) {
 	u.Fragment = ""
 }
+
+// RFC3986Modifiers returns every modifier that normalises a URL according to RFC 3986, in the order they should be
+// applied
+func RFC3986Modifiers() []URLModifyFunc {
+	return []URLModifyFunc{
+		LowercaseSchemeAndHost,
+		RemoveDefaultPort,
+		NormalizePercentEncoding,
+		RemoveDotSegments,
+		RemoveEmptyQuery,
+		SortQuery,
+	}
+}
+
+// LowercaseSchemeAndHost lowercases the scheme and host of the URL, which are case insensitive
+func LowercaseSchemeAndHost(u *url.URL) {
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+}
+
+// RemoveDefaultPort removes the port from the URL if it is the default for the scheme, ie 80 for http
+func RemoveDefaultPort(u *url.URL) {
+	if isDefaultPort(u.Scheme, u.Port()) {
+		host := u.Hostname()
+		if strings.Contains(host, ":") {
+			// IPv6 addresses must stay in brackets
+			host = "[" + host + "]"
+		}
+		u.Host = host
+	}
+}
+
+// isDefaultPort returns whether port is the default for the scheme, ie 80 for http
+func isDefaultPort(scheme string, port string) bool {
+	scheme = strings.ToLower(scheme)
+	return (scheme == "http" && port == "80") || (scheme == "https" && port == "443")
+}
+
+// RemoveDotSegments resolves any "." and ".." segments in the URLs path, ie "/a/./b/../c" becomes "/a/c"
+func RemoveDotSegments(u *url.URL) {
+	if u.Opaque != "" {
+		return
+	}
+	setEscapedPath(u, removeDotSegments(u.EscapedPath()))
+}
+
+// removeDotSegments implements the algorithm in RFC 3986 section 5.2.4
+func removeDotSegments(p string) string {
+	if !strings.Contains(p, ".") {
+		return p
+	}
+	in := p
+	out := make([]string, 0)
+	for in != "" {
+		switch {
+		case strings.HasPrefix(in, "../"):
+			in = in[3:]
+		case strings.HasPrefix(in, "./"):
+			in = in[2:]
+		case strings.HasPrefix(in, "/./"):
+			in = in[2:]
+		case in == "/.":
+			in = "/"
+		case strings.HasPrefix(in, "/../"):
+			in = in[3:]
+			if len(out) > 0 {
+				out = out[:len(out)-1]
+			}
+		case in == "/..":
+			in = "/"
+			if len(out) > 0 {
+				out = out[:len(out)-1]
+			}
+		case in == "." || in == "..":
+			in = ""
+		default:
+			// Move the first segment, including any leading slash, to the output
+			end := strings.IndexByte(in[1:], '/')
+			if end == -1 {
+				end = len(in)
+			} else {
+				end++
+			}
+			out = append(out, in[:end])
+			in = in[end:]
+		}
+	}
+	return strings.Join(out, "")
+}
+
+// NormalizePercentEncoding uppercases the hex digits of percent-encoded characters in the URLs path and query, and
+// decodes any characters that never need to be encoded, ie "%7e" becomes "~"
+func NormalizePercentEncoding(u *url.URL) {
+	if u.Opaque != "" {
+		return
+	}
+	setEscapedPath(u, normalizePercentEncoding(u.EscapedPath()))
+	u.RawQuery = normalizePercentEncoding(u.RawQuery)
+}
+
+// normalizePercentEncoding normalises each percent-encoded character in s
+func normalizePercentEncoding(s string) string {
+	if !strings.Contains(s, "%") {
+		return s
+	}
+	b := &strings.Builder{}
+	for i := 0; i < len(s); i++ {
+		if s[i] == '%' && i+2 < len(s) {
+			if c, err := strconv.ParseUint(s[i+1:i+3], 16, 8); err == nil {
+				if isUnreserved(byte(c)) {
+					b.WriteByte(byte(c))
+				} else {
+					b.WriteString("%" + strings.ToUpper(s[i+1:i+3]))
+				}
+				i += 2
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// isUnreserved returns whether c is an unreserved character in RFC 3986, which never needs to be percent-encoded
+func isUnreserved(c byte) bool {
+	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9') ||
+		c == '-' || c == '.' || c == '_' || c == '~'
+}
+
+// setEscapedPath sets the path of the URL from its escaped form, keeping the escaping if it isn't the default.
+// The URL is left unchanged if p is not a valid escaped path.
+func setEscapedPath(u *url.URL, p string) {
+	unescaped, err := url.PathUnescape(p)
+	if err != nil {
+		return
+	}
+	u.Path = unescaped
+	u.RawPath = ""
+	if u.EscapedPath() != p {
+		u.RawPath = p
+	}
+}
+
+// SortQuery sorts the URLs query parameters by name. Parameters with the same name keep their order.
+func SortQuery(u *url.URL) {
+	if u.RawQuery == "" {
+		return
+	}
+	params := strings.Split(u.RawQuery, "&")
+	sort.SliceStable(params, func(i, j int) bool {
+		return queryParamName(params[i]) < queryParamName(params[j])
+	})
+	u.RawQuery = strings.Join(params, "&")
+}
+
+// queryParamName returns the name of a raw query parameter, ie "a" for "a=1"
+func queryParamName(param string) string {
+	if i := strings.IndexByte(param, '='); i >= 0 {
+		return param[:i]
+	}
+	return param
+}
+
+// RemoveEmptyQuery removes empty parameters from the URLs query, and removes the "?" if the query is then empty
+func RemoveEmptyQuery(u *url.URL) {
+	params := make([]string, 0)
+	for _, param := range strings.Split(u.RawQuery, "&") {
+		if param != "" {
+			params = append(params, param)
+		}
+	}
+	u.RawQuery = strings.Join(params, "&")
+	if u.RawQuery == "" {
+		u.ForceQuery = false
+	}
+}
+
+// DefaultTrackingParams are the names of common tracking and session ID parameters, for use with StripQueryParams and
+// StripPathParams. A trailing * matches any name with that prefix.
+var DefaultTrackingParams = []string{
+	"utm_*", "gclid", "gclsrc", "dclid", "fbclid", "msclkid", "yclid", "igshid", "mc_cid", "mc_eid", "_ga", "_gl",
+	"jsessionid", "phpsessid", "aspsessionid*", "cfid", "cftoken",
+}
+
+// StripQueryParams returns a URLModifyFunc that removes query parameters matching any of the given names.
+// Names are case insensitive, and a trailing * matches any name with that prefix.
+func StripQueryParams(names ...string) URLModifyFunc {
+	match := paramMatcher(names)
+	return func(u *url.URL) {
+		filterQueryParams(u, func(name string) bool {
+			return !match(name)
+		})
+	}
+}
+
+// KeepQueryParams returns a URLModifyFunc that removes every query parameter that doesn't match one of the given
+// names. Names are case insensitive, and a trailing * matches any name with that prefix.
+func KeepQueryParams(names ...string) URLModifyFunc {
+	match := paramMatcher(names)
+	return func(u *url.URL) {
+		filterQueryParams(u, match)
+	}
+}
+
+// StripPathParams returns a URLModifyFunc that removes path parameters matching any of the given names, such as
+// ";jsessionid=1234" in "/page;jsessionid=1234". Names are matched in the same way as StripQueryParams.
+func StripPathParams(names ...string) URLModifyFunc {
+	match := paramMatcher(names)
+	return func(u *url.URL) {
+		if u.Opaque != "" || !strings.Contains(u.Path, ";") {
+			return
+		}
+		segments := strings.Split(u.EscapedPath(), "/")
+		for i, segment := range segments {
+			params := strings.Split(segment, ";")
+			kept := params[:1]
+			for _, param := range params[1:] {
+				if !match(queryParamName(param)) {
+					kept = append(kept, param)
+				}
+			}
+			segments[i] = strings.Join(kept, ";")
+		}
+		setEscapedPath(u, strings.Join(segments, "/"))
+	}
+}
+
+// paramMatcher returns a function that reports whether a parameter name matches any of the given names
+func paramMatcher(names []string) func(name string) bool {
+	exact := make(map[string]bool)
+	prefixes := make([]string, 0)
+	for _, n := range names {
+		n = strings.ToLower(n)
+		if strings.HasSuffix(n, "*") {
+			prefixes = append(prefixes, strings.TrimSuffix(n, "*"))
+		} else {
+			exact[n] = true
+		}
+	}
+	return func(name string) bool {
+		if unescaped, err := url.QueryUnescape(name); err == nil {
+			name = unescaped
+		}
+		name = strings.ToLower(name)
+		if exact[name] {
+			return true
+		}
+		for _, p := range prefixes {
+			if strings.HasPrefix(name, p) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// filterQueryParams keeps only the query parameters whose name passes keep, removing the "?" if none are left
+func filterQueryParams(u *url.URL, keep func(name string) bool) {
+	if u.RawQuery == "" {
+		return
+	}
+	params := make([]string, 0)
+	for _, param := range strings.Split(u.RawQuery, "&") {
+		if param != "" && keep(queryParamName(param)) {
+			params = append(params, param)
+		}
+	}
+	u.RawQuery = strings.Join(params, "&")
+	if u.RawQuery == "" {
+		u.ForceQuery = false
+	}
+}
@@ -173,3 +173,113 @@ func TestRemoveTrailingSlash(t *testing.T) {
 		})
 	}
 }
+
+func TestRFC3986Modifiers(t *testing.T) {
+	tests := []struct {
+		name     string
+		modifier internal.URLModifyFunc
+		u        string
+		want     string
+	}{
+		{name: "lowercase scheme and host", modifier: internal.LowercaseSchemeAndHost, u: "HTTP://Example.COM/Path", want: "http://example.com/Path"},
+		{name: "remove http port", modifier: internal.RemoveDefaultPort, u: "http://example.com:80/a", want: "http://example.com/a"},
+		{name: "remove https port", modifier: internal.RemoveDefaultPort, u: "https://example.com:443/a", want: "https://example.com/a"},
+		{name: "keep other port", modifier: internal.RemoveDefaultPort, u: "https://example.com:80/a", want: "https://example.com:80/a"},
+		{name: "remove ipv6 port", modifier: internal.RemoveDefaultPort, u: "http://[::1]:80/a", want: "http://[::1]/a"},
+		{name: "dot segments", modifier: internal.RemoveDotSegments, u: "http://example.com/a/./b/../c", want: "http://example.com/a/c"},
+		{name: "dot segments above root", modifier: internal.RemoveDotSegments, u: "http://example.com/../a/..", want: "http://example.com/"},
+		{name: "dot segments keep trailing slash", modifier: internal.RemoveDotSegments, u: "http://example.com/a/b/./", want: "http://example.com/a/b/"},
+		{name: "dot segments keep encoding", modifier: internal.RemoveDotSegments, u: "http://example.com/a%2Fb/./c", want: "http://example.com/a%2Fb/c"},
+		{name: "uppercase percent-encoding", modifier: internal.NormalizePercentEncoding, u: "http://example.com/a%2fb?q=%c3%a9", want: "http://example.com/a%2Fb?q=%C3%A9"},
+		{name: "decode unreserved", modifier: internal.NormalizePercentEncoding, u: "http://example.com/%7euser?q=%41", want: "http://example.com/~user?q=A"},
+		{name: "sort query", modifier: internal.SortQuery, u: "http://example.com/?b=1&a=2&b=0", want: "http://example.com/?a=2&b=1&b=0"},
+		{name: "remove empty query", modifier: internal.RemoveEmptyQuery, u: "http://example.com/a?", want: "http://example.com/a"},
+		{name: "remove empty params", modifier: internal.RemoveEmptyQuery, u: "http://example.com/a?&a=1&&b=2&", want: "http://example.com/a?a=1&b=2"},
+		{name: "opaque", modifier: internal.NormalizePercentEncoding, u: "mailto:test%40localhost", want: "mailto:test%40localhost"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := url.Parse(tt.u)
+			if err != nil {
+				t.Fatalf("url.Parse() error = %v", err)
+			}
+			if got := internal.ModifyURL(u, tt.modifier).String(); got != tt.want {
+				t.Errorf("ModifyURL() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRFC3986Modifiers_Equivalent(t *testing.T) {
+	a, _ := url.Parse("HTTP://Example.com:80/a/./b?b=1&a=2")
+	b, _ := url.Parse("http://example.com/a/b?a=2&b=1")
+	modifiers := internal.RFC3986Modifiers()
+	gotA := internal.ModifyURL(a, modifiers...).String()
+	gotB := internal.ModifyURL(b, modifiers...).String()
+	if gotA != gotB {
+		t.Errorf("ModifyURL() = %s and %s, want them to be equal", gotA, gotB)
+	}
+}
+
+func TestQueryParamModifiers(t *testing.T) {
+	tests := []struct {
+		name     string
+		modifier internal.URLModifyFunc
+		u        string
+		want     string
+	}{
+		{
+			name:     "strip default tracking params",
+			modifier: internal.StripQueryParams(internal.DefaultTrackingParams...),
+			u:        "https://example.com/a?utm_source=x&id=1&UTM_Medium=y&gclid=z&fbclid=z&PHPSESSID=s",
+			want:     "https://example.com/a?id=1",
+		},
+		{
+			name:     "strip every param",
+			modifier: internal.StripQueryParams(internal.DefaultTrackingParams...),
+			u:        "https://example.com/a?utm_source=x",
+			want:     "https://example.com/a",
+		},
+		{
+			name:     "strip custom params",
+			modifier: internal.StripQueryParams("ref", "sort"),
+			u:        "https://example.com/a?ref=home&page=2&sort=asc&utm_source=x",
+			want:     "https://example.com/a?page=2&utm_source=x",
+		},
+		{
+			name:     "keep params",
+			modifier: internal.KeepQueryParams("page", "q"),
+			u:        "https://example.com/a?ref=home&page=2&Q=term&utm_source=x",
+			want:     "https://example.com/a?page=2&Q=term",
+		},
+		{
+			name:     "keep no params",
+			modifier: internal.KeepQueryParams(),
+			u:        "https://example.com/a?page=2",
+			want:     "https://example.com/a",
+		},
+		{
+			name:     "strip path params",
+			modifier: internal.StripPathParams(internal.DefaultTrackingParams...),
+			u:        "https://example.com/shop;JSESSIONID=abc/item;color=red;jsessionid=def?utm_source=x",
+			want:     "https://example.com/shop/item;color=red?utm_source=x",
+		},
+		{
+			name:     "no path params",
+			modifier: internal.StripPathParams(internal.DefaultTrackingParams...),
+			u:        "https://example.com/a%2Fb",
+			want:     "https://example.com/a%2Fb",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := url.Parse(tt.u)
+			if err != nil {
+				t.Fatalf("url.Parse() error = %v", err)
+			}
+			if got := internal.ModifyURL(u, tt.modifier).String(); got != tt.want {
+				t.Errorf("ModifyURL() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package internal
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// URLPattern matches URLs against a regular expression, glob or prefix
+type URLPattern struct {
+	// source is the pattern as it was given, including the type, ie "glob:*.pdf"
+	source string
+	re     *regexp.Regexp
+	// subject returns the part of the URL that re is matched against
+	subject func(u *url.URL) string
+}
+
+// RegexpPattern returns a URLPattern that matches any URL containing a match of the regular expression expr
+func RegexpPattern(expr string) (URLPattern, error) {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return URLPattern{}, err
+	}
+	return URLPattern{source: "regexp:" + expr, re: re, subject: fullURL}, nil
+}
+
+// GlobPattern returns a URLPattern that matches URLs against a glob, where * matches anything except a slash, **
+// matches anything, and ? matches a single character except a slash.
+// A glob containing "://" is matched against the whole URL, a glob containing "/" is matched against the path, and
+// any other glob is matched against the last segment of the path, so "*.pdf" matches every PDF.
+func GlobPattern(glob string) URLPattern {
+	b := &strings.Builder{}
+	b.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch {
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case glob[i] == '*':
+			b.WriteString("[^/]*")
+		case glob[i] == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+	b.WriteString("$")
+
+	subject := lastPathSegment
+	if strings.Contains(glob, "://") {
+		subject = fullURL
+	} else if strings.Contains(glob, "/") {
+		subject = urlPath
+	}
+	return URLPattern{source: "glob:" + glob, re: regexp.MustCompile(b.String()), subject: subject}
+}
+
+// PrefixPattern returns a URLPattern that matches URLs starting with prefix. A prefix starting with "/" is matched
+// against the path, and any other prefix is matched against the whole URL.
+func PrefixPattern(prefix string) URLPattern {
+	subject := fullURL
+	if strings.HasPrefix(prefix, "/") {
+		subject = urlPath
+	}
+	re := regexp.MustCompile("^" + regexp.QuoteMeta(prefix))
+	return URLPattern{source: "prefix:" + prefix, re: re, subject: subject}
+}
+
+// ParseURLPattern parses a pattern with an optional type, ie "regexp:\.pdf$", "glob:/docs/**" or "prefix:/admin".
+// Patterns without a type are globs.
+func ParseURLPattern(s string) (URLPattern, error) {
+	switch {
+	case strings.HasPrefix(s, "regexp:"):
+		p, err := RegexpPattern(strings.TrimPrefix(s, "regexp:"))
+		if err != nil {
+			return URLPattern{}, fmt.Errorf("invalid pattern '%s': %w", s, err)
+		}
+		return p, nil
+	case strings.HasPrefix(s, "glob:"):
+		return GlobPattern(strings.TrimPrefix(s, "glob:")), nil
+	case strings.HasPrefix(s, "prefix:"):
+		return PrefixPattern(strings.TrimPrefix(s, "prefix:")), nil
+	default:
+		return GlobPattern(s), nil
+	}
+}
+
+// Match returns whether the URL matches the pattern
+func (p URLPattern) Match(u *url.URL) bool {
+	return p.re.MatchString(p.subject(u))
+}
+
+// String returns the pattern, including its type
+func (p URLPattern) String() string {
+	return p.source
+}
+
+// fullURL is matched against patterns that apply to the whole URL
+func fullURL(u *url.URL) string {
+	return u.String()
+}
+
+// urlPath is matched against patterns that apply to the path
+func urlPath(u *url.URL) string {
+	if u.Path == "" {
+		return "/"
+	}
+	return u.Path
+}
+
+// lastPathSegment is matched against patterns that apply to the final segment of the path
+func lastPathSegment(u *url.URL) string {
+	return path.Base(urlPath(u))
+}
+
+// PatternFilter returns a URLFilterFunc that filters out URLs that match any exclude pattern, or that don't match any
+// include pattern if there are some. onExclude is called with each URL that is filtered out and the reason why, and
+// may be nil.
+func PatternFilter(include []URLPattern, exclude []URLPattern, onExclude func(u *url.URL, reason string)) URLFilterFunc {
+	return func(urls []*url.URL) []*url.URL {
+		filtered := make([]*url.URL, 0)
+		for _, u := range urls {
+			reason := excludeReason(u, include, exclude)
+			if reason == "" {
+				filtered = append(filtered, u)
+				continue
+			}
+			if onExclude != nil {
+				onExclude(u, reason)
+			}
+		}
+		return filtered
+	}
+}
+
+// excludeReason returns why the URL is excluded by the patterns, or an empty string if it isn't
+func excludeReason(u *url.URL, include []URLPattern, exclude []URLPattern) string {
+	for _, p := range exclude {
+		if p.Match(u) {
+			return fmt.Sprintf("matched exclude %s", p)
+		}
+	}
+	if len(include) == 0 {
+		return ""
+	}
+	for _, p := range include {
+		if p.Match(u) {
+			return ""
+		}
+	}
+	return "didn't match an include pattern"
+}
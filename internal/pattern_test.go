@@ -0,0 +1,133 @@
+package internal_test
+
+import (
+	"github.com/jmwri/web-crawler/internal"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestParseURLPattern(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		matches []string
+		misses  []string
+		wantErr bool
+	}{
+		{
+			name:    "path glob",
+			pattern: "/docs/**",
+			matches: []string{"https://test.com/docs/a", "https://test.com/docs/a/b.html"},
+			misses:  []string{"https://test.com/docs", "https://test.com/blog/docs/a"},
+		},
+		{
+			name:    "single segment glob",
+			pattern: "glob:/docs/*",
+			matches: []string{"https://test.com/docs/a"},
+			misses:  []string{"https://test.com/docs/a/b.html"},
+		},
+		{
+			name:    "file name glob",
+			pattern: "*.pdf",
+			matches: []string{"https://test.com/a.pdf", "https://test.com/files/b.pdf"},
+			misses:  []string{"https://test.com/a.pdf.html", "https://test.com/pdf"},
+		},
+		{
+			name:    "url glob",
+			pattern: "https://*.test.com/**",
+			matches: []string{"https://docs.test.com/a"},
+			misses:  []string{"https://test.com/a", "http://docs.test.com/a"},
+		},
+		{
+			name:    "path prefix",
+			pattern: "prefix:/admin",
+			matches: []string{"https://test.com/admin", "https://test.com/admin/users", "https://test.com/administrator"},
+			misses:  []string{"https://test.com/a/admin"},
+		},
+		{
+			name:    "url prefix",
+			pattern: "prefix:https://test.com/a",
+			matches: []string{"https://test.com/a/b"},
+			misses:  []string{"http://test.com/a/b"},
+		},
+		{
+			name:    "regexp",
+			pattern: `regexp:\?page=\d+$`,
+			matches: []string{"https://test.com/blog?page=2"},
+			misses:  []string{"https://test.com/blog?page=last"},
+		},
+		{
+			name:    "invalid regexp",
+			pattern: "regexp:(",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := internal.ParseURLPattern(tt.pattern)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseURLPattern() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			for _, s := range tt.matches {
+				u, _ := url.Parse(s)
+				if !p.Match(u) {
+					t.Errorf("%s.Match(%s) = false, want true", p, s)
+				}
+			}
+			for _, s := range tt.misses {
+				u, _ := url.Parse(s)
+				if p.Match(u) {
+					t.Errorf("%s.Match(%s) = true, want false", p, s)
+				}
+			}
+		})
+	}
+}
+
+func TestPatternFilter(t *testing.T) {
+	urls := []*url.URL{
+		{Scheme: "https", Host: "test.com", Path: "/docs/intro"},
+		{Scheme: "https", Host: "test.com", Path: "/docs/manual.pdf"},
+		{Scheme: "https", Host: "test.com", Path: "/admin"},
+		{Scheme: "https", Host: "test.com", Path: "/blog"},
+	}
+	tests := []struct {
+		name         string
+		include      []internal.URLPattern
+		exclude      []internal.URLPattern
+		want         []*url.URL
+		wantExcluded map[string]string
+	}{
+		{
+			name:         "no patterns",
+			want:         urls,
+			wantExcluded: map[string]string{},
+		},
+		{
+			name:    "include and exclude",
+			include: []internal.URLPattern{internal.GlobPattern("/docs/**"), internal.PrefixPattern("/admin")},
+			exclude: []internal.URLPattern{internal.GlobPattern("*.pdf"), internal.PrefixPattern("/admin")},
+			want:    urls[:1],
+			wantExcluded: map[string]string{
+				"https://test.com/docs/manual.pdf": "matched exclude glob:*.pdf",
+				"https://test.com/admin":           "matched exclude prefix:/admin",
+				"https://test.com/blog":            "didn't match an include pattern",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			excluded := make(map[string]string)
+			f := internal.PatternFilter(tt.include, tt.exclude, func(u *url.URL, reason string) {
+				excluded[u.String()] = reason
+			})
+			if got := f(urls); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("PatternFilter() = %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(excluded, tt.wantExcluded) {
+				t.Errorf("PatternFilter() excluded = %v, want %v", excluded, tt.wantExcluded)
+			}
+		})
+	}
+}
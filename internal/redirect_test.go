@@ -0,0 +1,126 @@
+package internal_test
+
+import (
+	"context"
+	"github.com/jmwri/web-crawler/internal"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+// redirectSiteHandler serves a small site where some pages are only reachable through redirects
+var redirectSiteHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/index.html":
+		w.Write([]byte(`<a href="/alias">Home</a><a href="/docs">Docs</a>`))
+	case "/alias":
+		http.Redirect(w, r, "/index.html", http.StatusMovedPermanently)
+	case "/docs":
+		http.Redirect(w, r, "/docs-moved", http.StatusFound)
+	case "/docs-moved":
+		http.Redirect(w, r, "/docs/intro.html", http.StatusTemporaryRedirect)
+	case "/docs/intro.html":
+		w.Write([]byte(`<a href="usage.html">Usage</a>`))
+	case "/docs/usage.html":
+		w.Write([]byte(`<a href="intro.html">Intro</a>`))
+	default:
+		http.NotFound(w, r)
+	}
+})
+
+func TestHTTPGetLoader_Load_Redirects(t *testing.T) {
+	ts := httptest.NewServer(redirectSiteHandler)
+	defer ts.Close()
+
+	l := internal.NewHTTPGetLoader(ts.Client())
+	got, err := l.Load(context.Background(), ts.URL+"/docs")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	defer got.Body.Close()
+
+	wantRedirects := []internal.Redirect{
+		{URL: ts.URL + "/docs", StatusCode: http.StatusFound},
+		{URL: ts.URL + "/docs-moved", StatusCode: http.StatusTemporaryRedirect},
+	}
+	if !reflect.DeepEqual(got.Redirects, wantRedirects) {
+		t.Errorf("Load().Redirects = %v, want %v", got.Redirects, wantRedirects)
+	}
+	if got.URL.String() != ts.URL+"/docs/intro.html" {
+		t.Errorf("Load().URL = %s, want %s", got.URL, ts.URL+"/docs/intro.html")
+	}
+}
+
+func TestCrawl_Redirects(t *testing.T) {
+	ts := httptest.NewServer(redirectSiteHandler)
+	defer ts.Close()
+
+	target, _ := url.Parse(ts.URL + "/index.html")
+	loader := internal.NewHTTPGetLoader(ts.Client())
+	o := internal.NewCrawlOptions(target, true, 0, 1)
+
+	got, err := internal.Crawl(loader.Load, internal.HtmlTokenExtractor, o)
+	if err != nil {
+		t.Fatalf("Crawl() error = %v", err)
+	}
+
+	wantURLs := map[string][]string{
+		ts.URL + "/index.html":      {ts.URL + "/alias", ts.URL + "/docs"},
+		ts.URL + "/alias":           {},
+		ts.URL + "/docs":            {ts.URL + "/docs/usage.html"},
+		ts.URL + "/docs/usage.html": {ts.URL + "/docs/intro.html"},
+	}
+	if !reflect.DeepEqual(got.URLs(), wantURLs) {
+		t.Errorf("Crawl().URLs() got = %v, want %v", got.URLs(), wantURLs)
+	}
+
+	alias := got.Pages()[ts.URL+"/alias"]
+	if !alias.Duplicate {
+		t.Errorf("Pages()[/alias].Duplicate = false, want true")
+	}
+	if alias.FinalURL != ts.URL+"/index.html" {
+		t.Errorf("Pages()[/alias].FinalURL = %s, want %s", alias.FinalURL, ts.URL+"/index.html")
+	}
+
+	docs := got.Pages()[ts.URL+"/docs"]
+	if docs.Duplicate {
+		t.Errorf("Pages()[/docs].Duplicate = true, want false")
+	}
+	if len(docs.Redirects) != 2 {
+		t.Errorf("Pages()[/docs].Redirects = %v, want 2 hops", docs.Redirects)
+	}
+}
+
+func TestCrawl_Redirects_Modified(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/page", "/page/":
+			w.Write([]byte(`<a href="/moved">Moved</a>`))
+		case "/moved":
+			http.Redirect(w, r, "/page/", http.StatusMovedPermanently)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	target, _ := url.Parse(ts.URL + "/page")
+	loader := internal.NewHTTPGetLoader(ts.Client())
+	o := internal.NewCrawlOptions(target, true, 0, 1)
+
+	got, err := internal.Crawl(loader.Load, internal.HtmlTokenExtractor, o)
+	if err != nil {
+		t.Fatalf("Crawl() error = %v", err)
+	}
+
+	// /moved redirects to /page/, which is the same page as /page once the trailing slash is removed
+	moved := got.Pages()[ts.URL+"/moved"]
+	if !moved.Duplicate {
+		t.Errorf("Pages()[/moved].Duplicate = false, want true")
+	}
+	if moved.FinalURL != ts.URL+"/page/" {
+		t.Errorf("Pages()[/moved].FinalURL = %s, want %s", moved.FinalURL, ts.URL+"/page/")
+	}
+}
@@ -0,0 +1,216 @@
+package internal
+
+import (
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Page describes a single page that the crawler visited
+type Page struct {
+	// URL is the URL that was requested
+	URL string
+	// Depth is the depth that the page was discovered at. Seeds are at depth 1.
+	Depth int
+	// Kind is the kind of link that the page was discovered through. Only LinkKindNavigation pages are scraped.
+	Kind LinkKind
+	// Origin is the URL of the first page that linked to this one. It is empty for seeds.
+	Origin string
+	// Seed is the seed that the page was reached from. When several seeds link to the same page, it is the seed that
+	// reached it first.
+	Seed string
+	// StatusCode is the HTTP status code of the response. It is 0 if the page wasn't loaded over HTTP.
+	StatusCode int
+	// ContentType is the Content-Type of the response, if any
+	ContentType string
+	// Size is the number of bytes read from the response body
+	Size int64
+	// Latency is the time taken to load and read the page, including any retries
+	Latency time.Duration
+	// FinalURL is the URL of the page after any redirects
+	FinalURL string
+	// Redirects are the hops that were followed to reach FinalURL, in order
+	Redirects []Redirect
+	// Duplicate is true if FinalURL had already been reached through another URL, so the page wasn't scraped
+	Duplicate bool
+	// NoFollow is true if the page asked for none of its links to be followed, with a robots meta tag or
+	// X-Robots-Tag header. Its links are still recorded in Edges.
+	NoFollow bool
+	// NoIndex is true if the page asked not to be indexed, with a robots meta tag or X-Robots-Tag header
+	NoIndex bool
+	// Edges are the links found on the page, after filtering
+	Edges []Edge
+	// Err is present if the crawler failed to crawl the page
+	Err *PageError
+}
+
+// Edge is a link from a crawled page to another URL
+type Edge struct {
+	// URL is the URL that the link points to
+	URL string
+	// Kind is the type of resource that the link points to
+	Kind LinkKind
+	// NoFollow is true if the link has rel nofollow, ugc or sponsored, so it wasn't followed
+	NoFollow bool
+	// Text is the text inside an <a>, with whitespace collapsed
+	Text string
+	// Title is the title attribute of the link
+	Title string
+	// Rel contains each value of the rel attribute of the link, in lower case
+	Rel []string
+	// Target is the target attribute of the link, ie "_blank"
+	Target string
+	// Image is true if the link is an <a> containing an <img>
+	Image bool
+	// Alt is the alt text of the first <img> inside the link
+	Alt string
+}
+
+// Links returns the URL of each Edge on the page
+func (p Page) Links() []string {
+	links := make([]string, len(p.Edges))
+	for i, e := range p.Edges {
+		links[i] = e.URL
+	}
+	return links
+}
+
+// Result contains all of the URLs discovered and visited
+type Result struct {
+	// options are the options that the Crawler was executed against
+	options CrawlOptions
+	// pages is a map of URL to the Page crawled at that URL
+	pages map[string]Page
+	// excluded is a map of URL to the reason it was excluded by a pattern or robots.txt
+	excluded map[string]string
+	// truncateReason is why the crawl stopped early, if it did
+	truncateReason TruncateReason
+	// queueStats describes how the queue of URLs waiting to be crawled was used
+	queueStats QueueStats
+	// sitemaps are the sitemap URLs listed in the robots.txt files that were loaded
+	sitemaps []string
+	// mu is an internal mutex to ensure routine safe access of pages and excluded
+	mu *sync.Mutex
+}
+
+// Store a crawled Page
+func (r Result) Store(p Page) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pages[p.URL] = p
+}
+
+// snapshot returns a copy of the pages and excluded URLs that are stored so far
+func (r Result) snapshot() (map[string]Page, map[string]string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	pages := make(map[string]Page, len(r.pages))
+	for u, p := range r.pages {
+		pages[u] = p
+	}
+	excluded := make(map[string]string, len(r.excluded))
+	for u, reason := range r.excluded {
+		excluded[u] = reason
+	}
+	return pages, excluded
+}
+
+// Exclude records that a URL was excluded by a pattern or robots.txt. Only the first reason for each URL is kept.
+func (r Result) Exclude(u *url.URL, reason string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.excluded[u.String()]; !ok {
+		r.excluded[u.String()] = reason
+	}
+}
+
+// Target is the URL that the crawler started at
+func (r Result) Target() *url.URL {
+	return r.options.Target
+}
+
+// Seeds returns all of the URLs that the crawler started at, including Target
+func (r Result) Seeds() []*url.URL {
+	return r.options.seeds()
+}
+
+// SameDomain specifies if the crawler limited to the same domain, with any Scope other than ScopeAll
+func (r Result) SameDomain() bool {
+	return r.options.Scope != ScopeAll
+}
+
+// Scope returns the Scope that the crawler was limited to
+func (r Result) Scope() Scope {
+	return r.options.Scope
+}
+
+// MaxDepth returns the max depth of the crawler
+func (r Result) MaxDepth() int {
+	return r.options.MaxDepth
+}
+
+// Pages returns a map of URLs that the crawler visited, and the Page crawled at that URL
+func (r Result) Pages() map[string]Page {
+	return r.pages
+}
+
+// URLs returns a map of URLs that the crawler visited, and a list of URLs found on that page
+func (r Result) URLs() map[string][]string {
+	urls := make(map[string][]string, len(r.pages))
+	for u, p := range r.pages {
+		urls[u] = p.Links()
+	}
+	return urls
+}
+
+// Excluded returns a map of discovered URLs that were excluded by the Include or Exclude patterns, and seeds that were
+// disallowed by robots.txt, along with the reason why
+func (r Result) Excluded() map[string]string {
+	return r.excluded
+}
+
+// Truncated returns true if the crawl stopped before it visited every URL it found, because a budget ran out or the
+// crawl was cancelled
+func (r Result) Truncated() bool {
+	return r.truncateReason != NotTruncated
+}
+
+// TruncateReason returns why the crawl stopped early, or NotTruncated
+func (r Result) TruncateReason() TruncateReason {
+	return r.truncateReason
+}
+
+// QueueStats returns how the queue of URLs waiting to be crawled was used
+func (r Result) QueueStats() QueueStats {
+	return r.queueStats
+}
+
+// Sitemaps returns the sitemap URLs listed in the robots.txt files of the hosts that were crawled. It is empty if
+// robots.txt was ignored.
+func (r Result) Sitemaps() []string {
+	return r.sitemaps
+}
+
+// Errors returns a map of URLs that the crawler failed to crawl, and the reason why
+func (r Result) Errors() map[string]*PageError {
+	errs := make(map[string]*PageError)
+	for u, p := range r.pages {
+		if p.Err != nil {
+			errs[u] = p.Err
+		}
+	}
+	return errs
+}
+
+// FailedURLs returns a sorted list of URLs that the crawler failed to crawl
+func (r Result) FailedURLs() []string {
+	failed := make([]string, 0)
+	for u, p := range r.pages {
+		if p.Err != nil {
+			failed = append(failed, u)
+		}
+	}
+	sort.Strings(failed)
+	return failed
+}
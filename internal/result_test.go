@@ -0,0 +1,76 @@
+package internal_test
+
+import (
+	"github.com/jmwri/web-crawler/internal"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+)
+
+func TestResult_Pages(t *testing.T) {
+	ts := httptest.NewServer(testSiteHandler)
+	defer ts.Close()
+
+	target, _ := url.Parse(ts.URL + "/index.html")
+	loader := internal.NewHTTPGetLoader(ts.Client())
+	o := internal.NewCrawlOptions(target, true, 0, 1)
+
+	got, err := internal.Crawl(loader.Load, internal.HtmlTokenExtractor, o)
+	if err != nil {
+		t.Fatalf("Crawl() error = %v", err)
+	}
+
+	tests := []struct {
+		file       string
+		wantDepth  int
+		wantOrigin string
+	}{
+		{file: "index.html", wantDepth: 1, wantOrigin: ""},
+		{file: "about.html", wantDepth: 2, wantOrigin: ts.URL + "/index.html"},
+		{file: "contact.html", wantDepth: 2, wantOrigin: ts.URL + "/index.html"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			u := ts.URL + "/" + tt.file
+			p, ok := got.Pages()[u]
+			if !ok {
+				t.Fatalf("Pages() missing %s", u)
+			}
+			info, err := os.Stat("./testdata/html/" + tt.file)
+			if err != nil {
+				t.Fatalf("failed to stat test fixture: %s", err)
+			}
+			if p.URL != u {
+				t.Errorf("Page.URL = %s, want %s", p.URL, u)
+			}
+			if p.Depth != tt.wantDepth {
+				t.Errorf("Page.Depth = %d, want %d", p.Depth, tt.wantDepth)
+			}
+			if p.Origin != tt.wantOrigin {
+				t.Errorf("Page.Origin = %s, want %s", p.Origin, tt.wantOrigin)
+			}
+			if p.StatusCode != 200 {
+				t.Errorf("Page.StatusCode = %d, want %d", p.StatusCode, 200)
+			}
+			if p.ContentType != "text/html; charset=utf-8" {
+				t.Errorf("Page.ContentType = %s, want %s", p.ContentType, "text/html; charset=utf-8")
+			}
+			if p.Size != info.Size() {
+				t.Errorf("Page.Size = %d, want %d", p.Size, info.Size())
+			}
+			if p.Latency <= 0 {
+				t.Errorf("Page.Latency = %s, want > 0", p.Latency)
+			}
+			if p.FinalURL != u {
+				t.Errorf("Page.FinalURL = %s, want %s", p.FinalURL, u)
+			}
+			if len(p.Edges) != 3 {
+				t.Errorf("Page.Edges = %v, want 3 links", p.Edges)
+			}
+			if p.Err != nil {
+				t.Errorf("Page.Err = %v, want nil", p.Err)
+			}
+		})
+	}
+}
@@ -0,0 +1,374 @@
+package internal
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RobotsRules are the rules from a robots.txt file that apply to a single user agent
+type RobotsRules struct {
+	// rules are the Allow and Disallow rules for the user agent
+	rules []robotsRule
+	// disallowAll is set when the robots.txt file could not be loaded because of a server error
+	disallowAll bool
+	// CrawlDelay is the min time between requests that the user agent should wait. It is 0 if not set.
+	CrawlDelay time.Duration
+	// Sitemaps are the sitemap URLs listed in the robots.txt file, regardless of user agent
+	Sitemaps []string
+}
+
+// robotsRule is a single Allow or Disallow rule
+type robotsRule struct {
+	// allow is true for Allow rules, and false for Disallow rules
+	allow bool
+	// pattern is the path pattern as written in the robots.txt file
+	pattern string
+	// re matches paths that the pattern applies to
+	re *regexp.Regexp
+}
+
+// robotsGroup is a group of rules for one or more user agents
+type robotsGroup struct {
+	agents     []string
+	rules      []robotsRule
+	crawlDelay time.Duration
+}
+
+// ParseRobots parses a robots.txt file and returns the rules that apply to the given user agent.
+// The most specific matching user-agent group is used, falling back to the * group.
+func ParseRobots(r io.Reader, userAgent string) (RobotsRules, error) {
+	var groups []*robotsGroup
+	var sitemaps []string
+	var current *robotsGroup
+	// inAgents is true while reading consecutive user-agent lines, which all belong to the same group
+	inAgents := false
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		key := strings.ToLower(strings.TrimSpace(parts[0]))
+		value := strings.TrimSpace(parts[1])
+
+		switch key {
+		case "user-agent":
+			if !inAgents {
+				current = &robotsGroup{}
+				groups = append(groups, current)
+			}
+			current.agents = append(current.agents, strings.ToLower(value))
+			inAgents = true
+			continue
+		case "sitemap":
+			// Sitemaps aren't part of any group
+			if value != "" {
+				sitemaps = append(sitemaps, value)
+			}
+			continue
+		}
+		inAgents = false
+		if current == nil {
+			// Rules before the first user-agent line don't apply to anyone
+			continue
+		}
+
+		switch key {
+		case "allow", "disallow":
+			// An empty pattern matches nothing
+			if value == "" {
+				continue
+			}
+			current.rules = append(current.rules, robotsRule{
+				allow:   key == "allow",
+				pattern: value,
+				re:      robotsPattern(value),
+			})
+		case "crawl-delay":
+			if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+				current.crawlDelay = time.Duration(seconds * float64(time.Second))
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return RobotsRules{}, err
+	}
+
+	rules := RobotsRules{Sitemaps: sitemaps}
+	for _, g := range matchingRobotsGroups(groups, userAgent) {
+		rules.rules = append(rules.rules, g.rules...)
+		if g.crawlDelay > rules.CrawlDelay {
+			rules.CrawlDelay = g.crawlDelay
+		}
+	}
+	return rules, nil
+}
+
+// matchingRobotsGroups returns the groups with the most specific user agent that matches the given user agent.
+// Groups with the same user agent are all returned so that they can be merged.
+func matchingRobotsGroups(groups []*robotsGroup, userAgent string) []*robotsGroup {
+	// The product token must match the whole user-agent line, ignoring case, so "bot" doesn't match "web-crawler-bot"
+	token := robotsProductToken(userAgent)
+
+	var matched []*robotsGroup
+	best := ""
+	for _, g := range groups {
+		agent := ""
+		for _, a := range g.agents {
+			if (a == "*" || (token != "" && a == token)) && moreSpecificAgent(a, agent) {
+				agent = a
+			}
+		}
+		switch {
+		case agent == "":
+			continue
+		case agent == best:
+			matched = append(matched, g)
+		case moreSpecificAgent(agent, best):
+			matched = []*robotsGroup{g}
+			best = agent
+		}
+	}
+	return matched
+}
+
+// robotsProductToken returns the product token of a user agent in lower case, ie "web-crawler" from
+// "web-crawler/1.0 (+https://example.com)", which is the only part that robots rules are matched against
+func robotsProductToken(userAgent string) string {
+	token := strings.ToLower(strings.TrimSpace(userAgent))
+	if i := strings.IndexAny(token, "/ "); i >= 0 {
+		token = token[:i]
+	}
+	return token
+}
+
+// moreSpecificAgent returns whether user agent a is more specific than b. An empty b matches nothing.
+func moreSpecificAgent(a string, b string) bool {
+	switch {
+	case b == "":
+		return true
+	case a == "*":
+		return false
+	case b == "*":
+		return true
+	default:
+		return len(a) > len(b)
+	}
+}
+
+// robotsPattern converts a robots.txt path pattern to a regexp.
+// * matches any sequence of characters, and a trailing $ anchors the pattern to the end of the path.
+func robotsPattern(pattern string) *regexp.Regexp {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+
+	parts := strings.Split(pattern, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	expr := "^" + strings.Join(parts, ".*")
+	if anchored {
+		expr += "$"
+	}
+	return regexp.MustCompile(expr)
+}
+
+// Allowed returns whether the rules allow the given URL to be crawled.
+// The longest matching rule wins, and Allow wins if an Allow and Disallow rule are the same length.
+func (r RobotsRules) Allowed(u *url.URL) bool {
+	p := u.EscapedPath()
+	if p == "" {
+		p = "/"
+	}
+	if p == "/robots.txt" {
+		return true
+	}
+	if r.disallowAll {
+		return false
+	}
+	if u.RawQuery != "" {
+		p += "?" + u.RawQuery
+	}
+
+	allowed := true
+	longest := -1
+	for _, rule := range r.rules {
+		if !rule.re.MatchString(p) {
+			continue
+		}
+		if len(rule.pattern) > longest || (len(rule.pattern) == longest && rule.allow) {
+			allowed = rule.allow
+			longest = len(rule.pattern)
+		}
+	}
+	return allowed
+}
+
+// NewRobotsCache returns a new RobotsCache that loads robots.txt files with the given loader.
+// The loader must allow text/plain responses.
+// If limiter is not nil, any Crawl-delay for the user agent is applied to it.
+// Hosts in exemptHosts are always allowed, and their robots.txt files are never loaded.
+func NewRobotsCache(loader LoaderFunc, userAgent string, limiter *HostLimiter, exemptHosts ...string) *RobotsCache {
+	exempt := make(map[string]bool)
+	for _, h := range exemptHosts {
+		exempt[strings.ToLower(h)] = true
+	}
+	return &RobotsCache{
+		loader:    loader,
+		userAgent: userAgent,
+		limiter:   limiter,
+		exempt:    exempt,
+		hosts:     make(map[string]*robotsEntry),
+		mu:        &sync.Mutex{},
+	}
+}
+
+// RobotsCache loads and caches the robots.txt rules for each host
+type RobotsCache struct {
+	loader    LoaderFunc
+	userAgent string
+	limiter   *HostLimiter
+	// exempt contains hosts that robots.txt is ignored for
+	exempt map[string]bool
+	// hosts contains the rules for each scheme and host
+	hosts map[string]*robotsEntry
+	// sitemaps are the sitemap URLs listed in the robots.txt files that have been loaded, in the order they were found
+	sitemaps []string
+	// mu is an internal mutex to ensure routine safe access of hosts and sitemaps
+	mu *sync.Mutex
+}
+
+// robotsEntry holds the rules for a single host, which are only loaded once
+type robotsEntry struct {
+	once  sync.Once
+	rules RobotsRules
+	// loaded is set once rules have been loaded. It is guarded by RobotsCache.mu.
+	loaded bool
+}
+
+// Rules returns the robots.txt rules for the host of the given URL, loading them if they aren't cached.
+// Hosts that don't have a robots.txt file, or that can't be reached, allow everything.
+// Hosts that respond with a server error disallow everything.
+func (c *RobotsCache) Rules(ctx context.Context, u *url.URL) RobotsRules {
+	if c.exempt[strings.ToLower(u.Hostname())] {
+		return RobotsRules{}
+	}
+	robotsURL := &url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/robots.txt"}
+
+	c.mu.Lock()
+	entry, ok := c.hosts[robotsURL.String()]
+	if !ok {
+		entry = &robotsEntry{}
+		c.hosts[robotsURL.String()] = entry
+	}
+	c.mu.Unlock()
+
+	entry.once.Do(func() {
+		entry.rules = c.load(ctx, robotsURL)
+		c.addSitemaps(entry.rules.Sitemaps)
+		c.mu.Lock()
+		entry.loaded = true
+		c.mu.Unlock()
+		if c.limiter != nil && entry.rules.CrawlDelay > 0 {
+			c.limiter.SetHostInterval(u.Host, entry.rules.CrawlDelay)
+		}
+	})
+	return entry.rules
+}
+
+// loaded returns whether the robots.txt rules for the host of the given URL are already cached, so that Rules won't make
+// a request
+func (c *RobotsCache) loaded(u *url.URL) bool {
+	if c.exempt[strings.ToLower(u.Hostname())] {
+		return true
+	}
+	robotsURL := &url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/robots.txt"}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.hosts[robotsURL.String()]
+	return ok && entry.loaded
+}
+
+// addSitemaps records the sitemap URLs from a robots.txt file, skipping any that were listed by another host
+func (c *RobotsCache) addSitemaps(sitemaps []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, s := range sitemaps {
+		found := false
+		for _, existing := range c.sitemaps {
+			if existing == s {
+				found = true
+				break
+			}
+		}
+		if !found {
+			c.sitemaps = append(c.sitemaps, s)
+		}
+	}
+}
+
+// Sitemaps returns the sitemap URLs listed in every robots.txt file that has been loaded so far
+func (c *RobotsCache) Sitemaps() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	sitemaps := make([]string, len(c.sitemaps))
+	copy(sitemaps, c.sitemaps)
+	return sitemaps
+}
+
+// load the robots.txt file at the given URL
+func (c *RobotsCache) load(ctx context.Context, robotsURL *url.URL) RobotsRules {
+	res, err := c.loader(ctx, robotsURL.String())
+	if res != nil && res.Body != nil {
+		defer res.Body.Close()
+	}
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) && statusErr.StatusCode >= 500 {
+		return RobotsRules{disallowAll: true}
+	}
+	if err != nil {
+		return RobotsRules{}
+	}
+	rules, err := ParseRobots(res.Body, c.userAgent)
+	if err != nil {
+		return RobotsRules{}
+	}
+	return rules
+}
+
+// RobotsFilter returns a URLFilterFunc that filters out URLs disallowed by robots.txt
+func RobotsFilter(ctx context.Context, cache *RobotsCache) URLFilterFunc {
+	return robotsFilter(ctx, cache, func() bool { return false })
+}
+
+// robotsFilter returns a URLFilterFunc that filters out URLs disallowed by robots.txt. Once stopped returns true,
+// robots.txt is no longer loaded for new hosts, and their URLs are kept unchecked.
+func robotsFilter(ctx context.Context, cache *RobotsCache, stopped func() bool) URLFilterFunc {
+	return func(urls []*url.URL) []*url.URL {
+		filtered := make([]*url.URL, 0)
+		for _, u := range urls {
+			if stopped() && !cache.loaded(u) {
+				filtered = append(filtered, u)
+				continue
+			}
+			if !cache.Rules(ctx, u).Allowed(u) {
+				continue
+			}
+			filtered = append(filtered, u)
+		}
+		return filtered
+	}
+}
@@ -0,0 +1,270 @@
+package internal_test
+
+import (
+	"context"
+	"errors"
+	"github.com/jmwri/web-crawler/internal"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseRobots(t *testing.T) {
+	robots := `
+# Comments are ignored
+Sitemap: https://test.com/sitemap.xml
+
+User-agent: *
+Disallow: /private
+Disallow: /*.pdf$
+Allow: /private/public
+Crawl-delay: 5
+
+User-agent: web-crawler
+User-agent: other-crawler
+Disallow: /admin # Trailing comments are ignored
+Allow: /admin/login$
+Disallow: /search?
+Disallow: /*/drafts/
+Crawl-delay: 0.5
+
+User-agent: web-crawler-images
+Disallow: /
+
+User-agent: web-crawler
+Disallow: /merged
+
+Sitemap: https://test.com/other-sitemap.xml
+`
+	tests := []struct {
+		name           string
+		userAgent      string
+		wantAllowed    []string
+		wantDisallowed []string
+		wantCrawlDelay time.Duration
+	}{
+		{
+			name:           "wildcard agent",
+			userAgent:      "unknown-bot/1.0",
+			wantAllowed:    []string{"/", "/admin", "/private/public/page", "/file.pdf?download=1", "/robots.txt"},
+			wantDisallowed: []string{"/private", "/private/page", "/file.pdf", "/docs/file.pdf"},
+			wantCrawlDelay: time.Second * 5,
+		},
+		{
+			name:           "specific agent",
+			userAgent:      "web-crawler (+https://github.com/jmwri/web-crawler)",
+			wantAllowed:    []string{"/", "/private", "/file.pdf", "/admin/login", "/search", "/docs/published/"},
+			wantDisallowed: []string{"/admin", "/admin/login/reset", "/search?q=test", "/docs/drafts/1", "/merged"},
+			wantCrawlDelay: time.Millisecond * 500,
+		},
+		{
+			name:           "agent in shared group",
+			userAgent:      "Other-Crawler/2.0",
+			wantAllowed:    []string{"/", "/merged"},
+			wantDisallowed: []string{"/admin"},
+			wantCrawlDelay: time.Millisecond * 500,
+		},
+		{
+			name:           "agent must match whole token",
+			userAgent:      "other-crawler-bot/1.0",
+			wantAllowed:    []string{"/", "/admin"},
+			wantDisallowed: []string{"/private"},
+			wantCrawlDelay: time.Second * 5,
+		},
+		{
+			name:           "most specific agent",
+			userAgent:      "web-crawler-images/1.0",
+			wantAllowed:    []string{"/robots.txt"},
+			wantDisallowed: []string{"/", "/admin/login"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := internal.ParseRobots(strings.NewReader(robots), tt.userAgent)
+			if err != nil {
+				t.Fatalf("ParseRobots() error = %v", err)
+			}
+			for _, p := range tt.wantAllowed {
+				u, _ := url.Parse("https://test.com" + p)
+				if !got.Allowed(u) {
+					t.Errorf("Allowed(%s) = false, want true", p)
+				}
+			}
+			for _, p := range tt.wantDisallowed {
+				u, _ := url.Parse("https://test.com" + p)
+				if got.Allowed(u) {
+					t.Errorf("Allowed(%s) = true, want false", p)
+				}
+			}
+			if got.CrawlDelay != tt.wantCrawlDelay {
+				t.Errorf("CrawlDelay = %s, want %s", got.CrawlDelay, tt.wantCrawlDelay)
+			}
+			wantSitemaps := []string{"https://test.com/sitemap.xml", "https://test.com/other-sitemap.xml"}
+			if !reflect.DeepEqual(got.Sitemaps, wantSitemaps) {
+				t.Errorf("Sitemaps = %v, want %v", got.Sitemaps, wantSitemaps)
+			}
+		})
+	}
+}
+
+func TestRobotsCache_Rules(t *testing.T) {
+	var robotsRequests int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			robotsRequests++
+			testSiteHandler(w, r)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	loader := internal.NewHTTPGetLoader(ts.Client(), internal.WithContentTypes())
+	cache := internal.NewRobotsCache(loader.Load, "web-crawler", nil)
+	contact, _ := url.Parse(ts.URL + "/contact.html")
+
+	for i := 0; i < 3; i++ {
+		if cache.Rules(context.Background(), contact).Allowed(contact) {
+			t.Errorf("Allowed(%s) = true, want false", contact)
+		}
+	}
+	if robotsRequests != 1 {
+		t.Errorf("expecting robots.txt to be requested %d times, got %d", 1, robotsRequests)
+	}
+}
+
+func TestRobotsCache_Rules_Unavailable(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		wantAllowed bool
+	}{
+		{name: "not found allows everything", status: http.StatusNotFound, wantAllowed: true},
+		{name: "forbidden allows everything", status: http.StatusForbidden, wantAllowed: true},
+		{name: "server error disallows everything", status: http.StatusServiceUnavailable, wantAllowed: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+			}))
+			defer ts.Close()
+
+			loader := internal.NewHTTPGetLoader(ts.Client(), internal.WithContentTypes())
+			cache := internal.NewRobotsCache(loader.Load, "web-crawler", nil)
+			u, _ := url.Parse(ts.URL + "/page")
+
+			if got := cache.Rules(context.Background(), u).Allowed(u); got != tt.wantAllowed {
+				t.Errorf("Allowed(%s) = %v, want %v", u, got, tt.wantAllowed)
+			}
+		})
+	}
+}
+
+func TestCrawl_Robots(t *testing.T) {
+	ts := httptest.NewServer(testSiteHandler)
+	defer ts.Close()
+
+	tests := []struct {
+		name        string
+		userAgent   string
+		exemptHosts []string
+		want        map[string][]string
+	}{
+		{
+			name:      "follows rules for user agent",
+			userAgent: "web-crawler",
+			want: map[string][]string{
+				ts.URL + "/index.html": {ts.URL + "/index.html", ts.URL + "/about.html"},
+				ts.URL + "/about.html": {ts.URL + "/index.html", ts.URL + "/about.html"},
+			},
+		},
+		{
+			name:      "follows rules for any user agent",
+			userAgent: "unknown-bot",
+			want:      map[string][]string{},
+		},
+		{
+			name:        "exempt host",
+			userAgent:   "unknown-bot",
+			exemptHosts: []string{"127.0.0.1"},
+			want: map[string][]string{
+				ts.URL + "/index.html":   {ts.URL + "/index.html", ts.URL + "/about.html", ts.URL + "/contact.html"},
+				ts.URL + "/about.html":   {ts.URL + "/index.html", ts.URL + "/about.html", ts.URL + "/contact.html"},
+				ts.URL + "/contact.html": {ts.URL + "/index.html", ts.URL + "/about.html", ts.URL + "/contact.html"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target, _ := url.Parse(ts.URL + "/index.html")
+			loader := internal.NewHTTPGetLoader(ts.Client())
+			robotsLoader := internal.NewHTTPGetLoader(ts.Client(), internal.WithContentTypes())
+			limiter := internal.NewHostLimiter(0, 0, 0)
+			o := internal.NewCrawlOptions(target, true, 0, 2)
+			o.Robots = internal.NewRobotsCache(robotsLoader.Load, tt.userAgent, limiter, tt.exemptHosts...)
+
+			got, err := internal.Crawl(internal.LoaderWithHostLimiter(loader.Load, limiter), internal.HtmlTokenExtractor, o)
+			if err != nil {
+				t.Fatalf("Crawl() error = %v", err)
+			}
+			if !reflect.DeepEqual(got.URLs(), tt.want) {
+				t.Errorf("Crawl().URLs() got = %v, want %v", got.URLs(), tt.want)
+			}
+			wantExcluded := map[string]string{}
+			if len(tt.want) == 0 {
+				wantExcluded[target.String()] = "disallowed by robots.txt"
+			}
+			if !reflect.DeepEqual(got.Excluded(), wantExcluded) {
+				t.Errorf("Crawl().Excluded() got = %v, want %v", got.Excluded(), wantExcluded)
+			}
+			wantSitemaps := []string{"https://localhost/sitemap.xml"}
+			if len(tt.exemptHosts) > 0 {
+				wantSitemaps = []string{}
+			}
+			if !reflect.DeepEqual(got.Sitemaps(), wantSitemaps) {
+				t.Errorf("Crawl().Sitemaps() got = %v, want %v", got.Sitemaps(), wantSitemaps)
+			}
+		})
+	}
+}
+
+func TestCrawl_Robots_Stopped(t *testing.T) {
+	target := &url.URL{Scheme: "https", Host: "localhost", Path: "index.html"}
+	// Stop the crawl once robots.txt is requested for another host, which never responds
+	stop := make(chan struct{})
+	var robotsLoader internal.LoaderFunc = func(ctx context.Context, p string) (*internal.Response, error) {
+		if strings.HasPrefix(p, "https://localhost/") {
+			return nil, errors.New("no robots.txt")
+		}
+		close(stop)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	o := internal.NewCrawlOptions(target, true, 0, 2)
+	o.Scope = internal.ScopeAll
+	o.Robots = internal.NewRobotsCache(robotsLoader, "web-crawler", nil)
+	o.Stop = stop
+
+	done := make(chan internal.Result)
+	go func() {
+		got, err := internal.Crawl(testFileLoader, internal.HtmlTokenExtractor, o)
+		if err != nil {
+			t.Errorf("Crawl() error = %v", err)
+		}
+		done <- got
+	}()
+	select {
+	case got := <-done:
+		if got.TruncateReason() != internal.TruncatedStopped {
+			t.Errorf("Crawl().TruncateReason() = %s, want %s", got.TruncateReason(), internal.TruncatedStopped)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("Crawl() didn't return after the crawl was stopped")
+	}
+}
@@ -0,0 +1,108 @@
+package internal
+
+import (
+	"fmt"
+	"golang.org/x/net/publicsuffix"
+	"net/url"
+	"strings"
+)
+
+// Scope restricts which discovered URLs are crawled, relative to the seeds
+type Scope int
+
+const (
+	// ScopeAll crawls URLs on any host
+	ScopeAll Scope = iota
+	// ScopeHost only crawls URLs with exactly the same host and port as a seed
+	ScopeHost
+	// ScopeSubdomains only crawls URLs on the host of a seed, or any of its subdomains, with the same port as the seed
+	ScopeSubdomains
+	// ScopeRegistrableDomain only crawls URLs with the same registrable domain as a seed, according to the public
+	// suffix list, on any port. For example www.example.co.uk and docs.example.co.uk are both part of example.co.uk.
+	ScopeRegistrableDomain
+)
+
+// String returns the name of the Scope, as accepted by ParseScope
+func (s Scope) String() string {
+	switch s {
+	case ScopeAll:
+		return "all"
+	case ScopeHost:
+		return "host"
+	case ScopeSubdomains:
+		return "subdomains"
+	case ScopeRegistrableDomain:
+		return "domain"
+	default:
+		return fmt.Sprintf("Scope(%d)", int(s))
+	}
+}
+
+// ParseScope returns the Scope with the given name: all, host, subdomains or domain
+func ParseScope(name string) (Scope, error) {
+	for _, s := range []Scope{ScopeAll, ScopeHost, ScopeSubdomains, ScopeRegistrableDomain} {
+		if strings.EqualFold(name, s.String()) {
+			return s, nil
+		}
+	}
+	return ScopeAll, fmt.Errorf("unknown scope '%s', must be all, host, subdomains or domain", name)
+}
+
+// InScope returns whether u is within the scope of the seed. Unless scope is ScopeAll, URLs must have the same scheme
+// as the seed, unless ignoreScheme is true, in which case http and https are treated as the same site. ScopeHost and
+// ScopeSubdomains also require the same port, where a missing port is the same as the default for the scheme.
+func InScope(u *url.URL, seed *url.URL, scope Scope, ignoreScheme bool) bool {
+	if scope == ScopeAll {
+		return true
+	}
+	if !ignoreScheme && !strings.EqualFold(u.Scheme, seed.Scheme) {
+		return false
+	}
+	host, seedHost := strings.ToLower(u.Hostname()), strings.ToLower(seed.Hostname())
+	switch scope {
+	case ScopeHost:
+		return host == seedHost && scopePort(u) == scopePort(seed)
+	case ScopeSubdomains:
+		return (host == seedHost || strings.HasSuffix(host, "."+seedHost)) && scopePort(u) == scopePort(seed)
+	case ScopeRegistrableDomain:
+		return registrableDomain(u) == registrableDomain(seed)
+	default:
+		return false
+	}
+}
+
+// scopePort returns the port of the URL, or an empty string if it is missing or the default for the scheme. Default
+// ports are ignored so that http://example.com and https://example.com have the same port when the scheme is ignored.
+func scopePort(u *url.URL) string {
+	if port := u.Port(); !isDefaultPort(u.Scheme, port) {
+		return port
+	}
+	return ""
+}
+
+// registrableDomain returns the registrable domain of the URL, ie example.co.uk for www.example.co.uk.
+// Hosts without one, such as IP addresses and localhost, are returned as they are.
+func registrableDomain(u *url.URL) string {
+	host := strings.ToLower(u.Hostname())
+	domain, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return host
+	}
+	return domain
+}
+
+// ScopeFilter returns a URLFilterFunc that filters out URLs that aren't within the scope of any of the seeds
+func ScopeFilter(scope Scope, ignoreScheme bool, seeds ...*url.URL) URLFilterFunc {
+	return func(urls []*url.URL) []*url.URL {
+		filtered := make([]*url.URL, 0)
+		for _, u := range urls {
+			for _, seed := range seeds {
+				if InScope(u, seed, scope, ignoreScheme) {
+					filtered = append(filtered, u)
+					break
+				}
+			}
+		}
+		return filtered
+	}
+}
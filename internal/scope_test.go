@@ -0,0 +1,107 @@
+package internal_test
+
+import (
+	"github.com/jmwri/web-crawler/internal"
+	"net/url"
+	"testing"
+)
+
+func TestInScope(t *testing.T) {
+	seed := &url.URL{Scheme: "https", Host: "example.co.uk"}
+	tests := []struct {
+		name         string
+		u            string
+		ignoreScheme bool
+		want         map[internal.Scope]bool
+	}{
+		{
+			name: "same host",
+			u:    "https://EXAMPLE.co.uk/a",
+			want: map[internal.Scope]bool{internal.ScopeAll: true, internal.ScopeHost: true, internal.ScopeSubdomains: true, internal.ScopeRegistrableDomain: true},
+		},
+		{
+			name: "subdomain",
+			u:    "https://docs.example.co.uk/a",
+			want: map[internal.Scope]bool{internal.ScopeAll: true, internal.ScopeHost: false, internal.ScopeSubdomains: true, internal.ScopeRegistrableDomain: true},
+		},
+		{
+			name: "different port",
+			u:    "https://example.co.uk:8443/a",
+			want: map[internal.Scope]bool{internal.ScopeAll: true, internal.ScopeHost: false, internal.ScopeSubdomains: false, internal.ScopeRegistrableDomain: true},
+		},
+		{
+			name: "subdomain on different port",
+			u:    "https://docs.example.co.uk:8443/a",
+			want: map[internal.Scope]bool{internal.ScopeAll: true, internal.ScopeHost: false, internal.ScopeSubdomains: false, internal.ScopeRegistrableDomain: true},
+		},
+		{
+			name: "default port",
+			u:    "https://example.co.uk:443/a",
+			want: map[internal.Scope]bool{internal.ScopeAll: true, internal.ScopeHost: true, internal.ScopeSubdomains: true, internal.ScopeRegistrableDomain: true},
+		},
+		{
+			name: "suffix without a dot",
+			u:    "https://myexample.co.uk/a",
+			want: map[internal.Scope]bool{internal.ScopeAll: true, internal.ScopeHost: false, internal.ScopeSubdomains: false, internal.ScopeRegistrableDomain: false},
+		},
+		{
+			name: "other domain with same public suffix",
+			u:    "https://other.co.uk/a",
+			want: map[internal.Scope]bool{internal.ScopeAll: true, internal.ScopeHost: false, internal.ScopeSubdomains: false, internal.ScopeRegistrableDomain: false},
+		},
+		{
+			name: "http",
+			u:    "http://www.example.co.uk/a",
+			want: map[internal.Scope]bool{internal.ScopeAll: true, internal.ScopeHost: false, internal.ScopeSubdomains: false, internal.ScopeRegistrableDomain: false},
+		},
+		{
+			name:         "http ignoring scheme",
+			u:            "http://www.example.co.uk/a",
+			ignoreScheme: true,
+			want:         map[internal.Scope]bool{internal.ScopeAll: true, internal.ScopeHost: false, internal.ScopeSubdomains: true, internal.ScopeRegistrableDomain: true},
+		},
+		{
+			name:         "http default port ignoring scheme",
+			u:            "http://example.co.uk:80/a",
+			ignoreScheme: true,
+			want:         map[internal.Scope]bool{internal.ScopeAll: true, internal.ScopeHost: true, internal.ScopeSubdomains: true, internal.ScopeRegistrableDomain: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := url.Parse(tt.u)
+			if err != nil {
+				t.Fatalf("url.Parse() error = %v", err)
+			}
+			for scope, want := range tt.want {
+				if got := internal.InScope(u, seed, scope, tt.ignoreScheme); got != want {
+					t.Errorf("InScope(%s, %s) = %v, want %v", u, scope, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestInScope_RegistrableDomainWithoutSuffix(t *testing.T) {
+	seed := &url.URL{Scheme: "http", Host: "127.0.0.1:8080"}
+	same := &url.URL{Scheme: "http", Host: "127.0.0.1:9090"}
+	other := &url.URL{Scheme: "http", Host: "127.0.0.2:8080"}
+	if !internal.InScope(same, seed, internal.ScopeRegistrableDomain, false) {
+		t.Errorf("InScope(%s) = false, want true", same)
+	}
+	if internal.InScope(other, seed, internal.ScopeRegistrableDomain, false) {
+		t.Errorf("InScope(%s) = true, want false", other)
+	}
+}
+
+func TestParseScope(t *testing.T) {
+	for _, scope := range []internal.Scope{internal.ScopeAll, internal.ScopeHost, internal.ScopeSubdomains, internal.ScopeRegistrableDomain} {
+		got, err := internal.ParseScope(scope.String())
+		if err != nil || got != scope {
+			t.Errorf("ParseScope(%s) = %v, %v, want %v", scope, got, err, scope)
+		}
+	}
+	if _, err := internal.ParseScope("galaxy"); err == nil {
+		t.Errorf("ParseScope(galaxy) error = nil, want error")
+	}
+}
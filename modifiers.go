@@ -0,0 +1,72 @@
+package web_crawler
+
+import (
+	"github.com/jmwri/web-crawler/internal"
+	"net/url"
+)
+
+// RFC3986Modifiers returns every Modifier that normalises a URL according to RFC 3986, in the order they should be
+// applied. Pass them to WithModifiers, or pick the ones you want individually.
+func RFC3986Modifiers() []Modifier {
+	return []Modifier{
+		LowercaseSchemeAndHost,
+		RemoveDefaultPort,
+		NormalizePercentEncoding,
+		RemoveDotSegments,
+		RemoveEmptyQuery,
+		SortQuery,
+	}
+}
+
+// LowercaseSchemeAndHost lowercases the scheme and host of the URL, which are case insensitive
+func LowercaseSchemeAndHost(u *url.URL) {
+	internal.LowercaseSchemeAndHost(u)
+}
+
+// RemoveDefaultPort removes the port from the URL if it is the default for the scheme, ie 80 for http
+func RemoveDefaultPort(u *url.URL) {
+	internal.RemoveDefaultPort(u)
+}
+
+// NormalizePercentEncoding uppercases the hex digits of percent-encoded characters in the URLs path and query, and
+// decodes any characters that never need to be encoded, ie "%7e" becomes "~"
+func NormalizePercentEncoding(u *url.URL) {
+	internal.NormalizePercentEncoding(u)
+}
+
+// RemoveDotSegments resolves any "." and ".." segments in the URLs path, ie "/a/./b/../c" becomes "/a/c"
+func RemoveDotSegments(u *url.URL) {
+	internal.RemoveDotSegments(u)
+}
+
+// RemoveEmptyQuery removes empty parameters from the URLs query, and removes the "?" if the query is then empty
+func RemoveEmptyQuery(u *url.URL) {
+	internal.RemoveEmptyQuery(u)
+}
+
+// SortQuery sorts the URLs query parameters by name. Parameters with the same name keep their order.
+func SortQuery(u *url.URL) {
+	internal.SortQuery(u)
+}
+
+// DefaultTrackingParams are the names of common tracking and session ID parameters, for use with StripQueryParams and
+// StripPathParams. A trailing * matches any name with that prefix.
+var DefaultTrackingParams = internal.DefaultTrackingParams
+
+// StripQueryParams returns a Modifier that removes query parameters matching any of the given names.
+// Names are case insensitive, and a trailing * matches any name with that prefix.
+func StripQueryParams(names ...string) Modifier {
+	return Modifier(internal.StripQueryParams(names...))
+}
+
+// KeepQueryParams returns a Modifier that removes every query parameter that doesn't match one of the given names.
+// Names are case insensitive, and a trailing * matches any name with that prefix.
+func KeepQueryParams(names ...string) Modifier {
+	return Modifier(internal.KeepQueryParams(names...))
+}
+
+// StripPathParams returns a Modifier that removes path parameters matching any of the given names, such as
+// ";jsessionid=1234" in "/page;jsessionid=1234". Names are matched in the same way as StripQueryParams.
+func StripPathParams(names ...string) Modifier {
+	return Modifier(internal.StripPathParams(names...))
+}
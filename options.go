@@ -0,0 +1,216 @@
+package web_crawler
+
+import (
+	"context"
+	"github.com/jmwri/web-crawler/internal"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Loader loads the requested page
+type Loader func(ctx context.Context, p string) (*Response, error)
+
+// Response is a page loaded by a Loader
+type Response = internal.Response
+
+// Extractor extracts links from the given io.Reader
+type Extractor func(r io.Reader) (Document, error)
+
+// Document contains everything an Extractor found on a page
+type Document = internal.Document
+
+// Link is a single link found on a page by an Extractor
+type Link = internal.Link
+
+// Filter returns filtered URLs
+type Filter func(urls []*url.URL) []*url.URL
+
+// Modifier modifies the given URL in place
+type Modifier func(u *url.URL)
+
+// Backoff returns the duration to sleep before making an attempt
+type Backoff func(attempt int) time.Duration
+
+// DefaultUserAgent is the User-Agent sent with requests, and matched against robots.txt, unless WithUserAgent is given
+const DefaultUserAgent = "web-crawler (+https://github.com/jmwri/web-crawler)"
+
+// ExponentialBackoff returns a Backoff that doubles the duration for each attempt, starting from base.
+// The duration is capped at max, unless max is 0. jitter is the fraction of the duration, between 0 and 1, that is
+// randomly removed so that retries from many workers are spread out.
+func ExponentialBackoff(base time.Duration, max time.Duration, jitter float64) Backoff {
+	return Backoff(internal.ExponentialBackoff(base, max, jitter))
+}
+
+// Option configures a Crawler created with NewCrawler
+type Option func(c *config)
+
+// config holds everything that can be configured by an Option
+type config struct {
+	client    *http.Client
+	loader    Loader
+	extractor Extractor
+	backoff   Backoff
+	attempts  int
+	// maxRetryAfter and retryable configure the retry policy
+	maxRetryAfter time.Duration
+	retryable     func(err error) bool
+	filters       []Filter
+	modifiers     []Modifier
+	// rate, delay and hostConcurrency configure the per host limiter
+	rate            float64
+	delay           time.Duration
+	hostConcurrency int
+	userAgent       string
+	ignoreRobots    bool
+	robotsExempt    []string
+	contentTypes    []string
+	headProbe       bool
+}
+
+// defaultConfig returns the config used by DefaultCrawler
+func defaultConfig() config {
+	return config{
+		client:        http.DefaultClient,
+		extractor:     internal.HtmlTokenExtractor,
+		backoff:       internal.SimpleBackoff,
+		attempts:      5,
+		userAgent:     DefaultUserAgent,
+		contentTypes:  internal.DefaultContentTypes,
+		maxRetryAfter: time.Minute,
+	}
+}
+
+// WithHTTPClient sets the client used to load pages. It has no effect if WithLoader is also given.
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *config) {
+		if client != nil {
+			c.client = client
+		}
+	}
+}
+
+// WithLoader sets the Loader used to load pages, replacing the default HTTP GET loader
+func WithLoader(l Loader) Option {
+	return func(c *config) {
+		c.loader = l
+	}
+}
+
+// WithExtractor sets the Extractor used to find links on a page
+func WithExtractor(e Extractor) Option {
+	return func(c *config) {
+		if e != nil {
+			c.extractor = e
+		}
+	}
+}
+
+// WithBackoff sets the Backoff used between attempts to load a page
+func WithBackoff(b Backoff) Option {
+	return func(c *config) {
+		if b != nil {
+			c.backoff = b
+		}
+	}
+}
+
+// WithMaxAttempts sets the number of attempts made to load each page, including the first.
+// Set to 1 to disable retries.
+func WithMaxAttempts(attempts int) Option {
+	return func(c *config) {
+		if attempts < 1 {
+			attempts = 1
+		}
+		c.attempts = attempts
+	}
+}
+
+// WithMaxRetryAfter caps how long a Retry-After header can make the crawler wait before retrying a page.
+// Set to 0 for no cap. Defaults to 1 minute.
+func WithMaxRetryAfter(d time.Duration) Option {
+	return func(c *config) {
+		c.maxRetryAfter = d
+	}
+}
+
+// WithRetryable sets the func that decides whether a failed attempt to load a page should be retried.
+// By default network errors, 5xx, 408 and 429 responses are retried, and all other errors are final.
+func WithRetryable(retryable func(err error) bool) Option {
+	return func(c *config) {
+		c.retryable = retryable
+	}
+}
+
+// WithFilters adds Filters that are applied to discovered URLs after the standard filters
+func WithFilters(filters ...Filter) Option {
+	return func(c *config) {
+		c.filters = append(c.filters, filters...)
+	}
+}
+
+// WithModifiers adds Modifiers that are applied to discovered URLs before the standard modifiers, which remove the
+// fragment and trailing slash
+func WithModifiers(modifiers ...Modifier) Option {
+	return func(c *config) {
+		c.modifiers = append(c.modifiers, modifiers...)
+	}
+}
+
+// WithRateLimit limits requests to each host to rps requests per second. Set to 0 for no limit.
+func WithRateLimit(rps float64) Option {
+	return func(c *config) {
+		c.rate = rps
+	}
+}
+
+// WithCrawlDelay sets the min time between the start of requests to each host. Set to 0 for no delay.
+func WithCrawlDelay(delay time.Duration) Option {
+	return func(c *config) {
+		c.delay = delay
+	}
+}
+
+// WithHostConcurrency limits the number of requests to each host at once. Set to 0 for no limit.
+func WithHostConcurrency(n int) Option {
+	return func(c *config) {
+		c.hostConcurrency = n
+	}
+}
+
+// WithUserAgent sets the User-Agent sent with requests, which is also used to pick the rules to follow from robots.txt
+// and X-Robots-Tag headers. The User-Agent is only sent by the default loader.
+func WithUserAgent(userAgent string) Option {
+	return func(c *config) {
+		c.userAgent = userAgent
+	}
+}
+
+// WithIgnoreRobots ignores robots.txt for the given hosts, or for every host if none are given.
+// It should only be used for sites that you own.
+func WithIgnoreRobots(hosts ...string) Option {
+	return func(c *config) {
+		if len(hosts) == 0 {
+			c.ignoreRobots = true
+			return
+		}
+		c.robotsExempt = append(c.robotsExempt, hosts...)
+	}
+}
+
+// WithContentTypes sets the media types that the default loader will extract links from, ie "text/html".
+// Pages of any other type are recorded with ErrorKindNonHTML. If no types are given then every type is allowed.
+func WithContentTypes(types ...string) Option {
+	return func(c *config) {
+		c.contentTypes = types
+	}
+}
+
+// WithHeadProbe makes the default loader send a HEAD request before each GET request, so that pages with a
+// content type that isn't allowed are never downloaded
+func WithHeadProbe() Option {
+	return func(c *config) {
+		c.headProbe = true
+	}
+}
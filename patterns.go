@@ -0,0 +1,43 @@
+package web_crawler
+
+import "github.com/jmwri/web-crawler/internal"
+
+// URLPattern matches URLs against a regular expression, glob or prefix
+type URLPattern = internal.URLPattern
+
+// RegexpPattern returns a URLPattern that matches any URL containing a match of the regular expression expr
+func RegexpPattern(expr string) (URLPattern, error) {
+	return internal.RegexpPattern(expr)
+}
+
+// GlobPattern returns a URLPattern that matches URLs against a glob, where * matches anything except a slash, **
+// matches anything, and ? matches a single character except a slash.
+// A glob containing "://" is matched against the whole URL, a glob containing "/" is matched against the path, and
+// any other glob is matched against the last segment of the path, so "*.pdf" matches every PDF.
+func GlobPattern(glob string) URLPattern {
+	return internal.GlobPattern(glob)
+}
+
+// PrefixPattern returns a URLPattern that matches URLs starting with prefix. A prefix starting with "/" is matched
+// against the path, and any other prefix is matched against the whole URL.
+func PrefixPattern(prefix string) URLPattern {
+	return internal.PrefixPattern(prefix)
+}
+
+// ParseURLPattern parses a pattern with an optional type, ie "regexp:\.pdf$", "glob:/docs/**" or "prefix:/admin".
+// Patterns without a type are globs.
+func ParseURLPattern(s string) (URLPattern, error) {
+	return internal.ParseURLPattern(s)
+}
+
+// IncludeFilter returns a Filter that filters out URLs that don't match any of the patterns.
+// Use CrawlConfig.Include instead to have the excluded URLs reported in the Result.
+func IncludeFilter(patterns ...URLPattern) Filter {
+	return Filter(internal.PatternFilter(patterns, nil, nil))
+}
+
+// ExcludeFilter returns a Filter that filters out URLs that match any of the patterns.
+// Use CrawlConfig.Exclude instead to have the excluded URLs reported in the Result.
+func ExcludeFilter(patterns ...URLPattern) Filter {
+	return Filter(internal.PatternFilter(nil, patterns, nil))
+}
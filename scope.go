@@ -0,0 +1,23 @@
+package web_crawler
+
+import "github.com/jmwri/web-crawler/internal"
+
+// Scope restricts which discovered URLs are crawled, relative to the seeds
+type Scope = internal.Scope
+
+const (
+	// ScopeAll crawls URLs on any host
+	ScopeAll = internal.ScopeAll
+	// ScopeHost only crawls URLs with exactly the same host and port as a seed
+	ScopeHost = internal.ScopeHost
+	// ScopeSubdomains only crawls URLs on the host of a seed, or any of its subdomains, with the same port as the seed
+	ScopeSubdomains = internal.ScopeSubdomains
+	// ScopeRegistrableDomain only crawls URLs with the same registrable domain as a seed, according to the public
+	// suffix list, on any port. For example www.example.co.uk and docs.example.co.uk are both part of example.co.uk.
+	ScopeRegistrableDomain = internal.ScopeRegistrableDomain
+)
+
+// ParseScope returns the Scope with the given name: all, host, subdomains or domain
+func ParseScope(name string) (Scope, error) {
+	return internal.ParseScope(name)
+}